@@ -0,0 +1,113 @@
+package mysqldump
+
+import "strings"
+
+// clientCharset follows the character_set_client of a restore session through its
+// SET statements, including the values saved in and restored from user variables
+// by the dump header and around triggers
+type clientCharset struct {
+	current string
+	// user variables holding a saved character_set_client
+	saved map[string]string
+}
+
+// follow updates the character set from a statement, and reports whether it changed
+func (c *clientCharset) follow(text string) bool {
+	if !containsFold(text, "SET") {
+		return false
+	}
+	tokens := tokenize(text, -1)
+	if len(tokens) < 2 || !tokens[0].is("SET") {
+		return false
+	}
+
+	before := c.current
+	start := 1
+	for i := 1; i <= len(tokens); i++ {
+		if i < len(tokens) && tokens[i].text != "," {
+			continue
+		}
+		c.assign(tokens[start:i])
+		start = i + 1
+	}
+	return c.current != before
+}
+
+// assign applies one assignment of a SET statement
+func (c *clientCharset) assign(a []sqlToken) {
+	switch {
+	case len(a) >= 2 && a[0].is("NAMES"):
+		c.set(a[1])
+		return
+	case len(a) >= 3 && a[0].is("CHARACTER") && a[1].is("SET"):
+		c.set(a[2])
+		return
+	case len(a) >= 2 && a[0].is("CHARSET"):
+		c.set(a[1])
+		return
+	}
+
+	eq := -1
+	for i, tok := range a {
+		if tok.text == "=" {
+			eq = i
+			break
+		}
+	}
+	if eq < 1 || eq+1 >= len(a) {
+		return
+	}
+	lhs, rhs := a[:eq], a[eq+1:]
+	if len(lhs) > 0 && lhs[len(lhs)-1].text == ":" {
+		// :=
+		lhs = lhs[:len(lhs)-1]
+	}
+	if len(lhs) == 0 {
+		return
+	}
+
+	if user, ok := userVariable(lhs); ok {
+		// SET @saved = @@character_set_client
+		if !isUserVariable(rhs) && strings.EqualFold(rhs[len(rhs)-1].text, "character_set_client") {
+			if c.saved == nil {
+				c.saved = make(map[string]string)
+			}
+			c.saved[user] = c.current
+		}
+		return
+	}
+	if !strings.EqualFold(lhs[len(lhs)-1].text, "character_set_client") {
+		return
+	}
+	if user, ok := userVariable(rhs); ok {
+		if saved, ok := c.saved[user]; ok {
+			c.current = saved
+		}
+		return
+	}
+	c.set(rhs[0])
+}
+
+// set makes the character set named by tok current
+func (c *clientCharset) set(tok sqlToken) {
+	name := tok.text
+	if tok.str {
+		name = strings.Trim(name, `'"`)
+	}
+	if name == "" || tok.is("DEFAULT") {
+		return
+	}
+	c.current = strings.ToLower(name)
+}
+
+// userVariable returns the name of the user variable @name made of tokens
+func userVariable(tokens []sqlToken) (string, bool) {
+	if !isUserVariable(tokens) {
+		return "", false
+	}
+	return strings.ToLower(tokens[1].text), true
+}
+
+func isUserVariable(tokens []sqlToken) bool {
+	return len(tokens) == 2 && tokens[0].text == "@" && tokens[1].text != "@"
+}
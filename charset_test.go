@@ -0,0 +1,44 @@
+package mysqldump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_clientCharset_follow(t *testing.T) {
+	var c clientCharset
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */", want: ""},
+		{text: " SET NAMES sjis ", want: "sjis"},
+		{text: "INSERT INTO t VALUES ('SET NAMES utf8')", want: "sjis"},
+		{text: "/*!50003 SET @saved_cs_client = @@character_set_client */", want: "sjis"},
+		{text: "/*!50003 SET character_set_client = utf8mb4 */", want: "utf8mb4"},
+		{text: "/*!50003 SET character_set_client = @saved_cs_client */", want: "sjis"},
+		{text: "SET SESSION sql_mode='', CHARACTER SET 'GBK'", want: "gbk"},
+		{text: "/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */", want: ""},
+		{text: "SET NAMES DEFAULT", want: ""},
+	}
+	for _, tt := range tests {
+		c.follow(tt.text)
+		if c.current != tt.want {
+			t.Errorf("after %q charset = %q, want %q", tt.text, c.current, tt.want)
+		}
+	}
+}
+
+func Test_restorer_charset(t *testing.T) {
+	r := newDryRunRestorer("db")
+	handleAll(t, r, "/*!40101 SET NAMES sjis */;\nINSERT INTO `t` VALUES ('\x83\x5C');\nINSERT INTO `t` VALUES ('b');\n")
+
+	var got []string
+	for _, s := range r.report.Plan.Statements {
+		got = append(got, s.Statement)
+	}
+	want := []string{"/*!40101 SET NAMES sjis */", "INSERT INTO `t` VALUES ('\x83\x5C')", "INSERT INTO `t` VALUES ('b')"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %q\nwant %q", got, want)
+	}
+}
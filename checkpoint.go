@@ -0,0 +1,123 @@
+package mysqldump
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// size of the input prefix hashed to detect a changed input
+const checkpointPrefixSize = 1 << 20
+
+// ErrCheckpointMismatch is returned when the Source input differs from the one the checkpoint was written for
+var ErrCheckpointMismatch = errors.New("restore checkpoint does not match the input")
+
+type (
+	// restoreCheckpoint is persisted by WithRestoreCheckpoint after each commit
+	restoreCheckpoint struct {
+		RestorePosition
+		// SET and USE statements executed before the position, replayed on resume
+		Session []string
+		// database selected by the input at the position, before WithDatabaseMap
+		Database string
+		// dump section the position is in
+		Section      sectionKind
+		SectionTable string
+		// size and SHA-256 of the first bytes of the input
+		PrefixSize int64
+		PrefixHash string
+	}
+
+	// checkpointer loads and saves the checkpoint of a restore
+	checkpointer struct {
+		path       string
+		prefixSize int64
+		prefixHash string
+		// checkpoint found when starting, nil for a new restore
+		resume *restoreCheckpoint
+	}
+)
+
+// openCheckpoint loads the checkpoint at path, if any, and positions reader after it.
+//
+// The returned reader continues from the checkpoint when the input is seekable,
+// otherwise it starts from the beginning and the already applied statements have to be skipped.
+// The returned bool reports whether the reader was moved to the checkpoint.
+func openCheckpoint(path string, reader io.Reader) (io.Reader, *checkpointer, bool, error) {
+	cp := &checkpointer{path: path}
+
+	var (
+		base   int64
+		seeker io.Seeker
+	)
+	if s, ok := reader.(io.Seeker); ok {
+		if pos, err := s.Seek(0, io.SeekCurrent); err == nil {
+			seeker, base = s, pos
+		}
+	}
+
+	prefix := make([]byte, checkpointPrefixSize)
+	n, err := io.ReadFull(reader, prefix)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, false, err
+	}
+	prefix = prefix[:n]
+	sum := sha256.Sum256(prefix)
+	cp.prefixSize = int64(n)
+	cp.prefixHash = hex.EncodeToString(sum[:])
+	input := io.MultiReader(bytes.NewReader(prefix), reader)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return input, cp, false, nil
+		}
+		return nil, nil, false, err
+	}
+
+	var state restoreCheckpoint
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, nil, false, fmt.Errorf("restore checkpoint %s: %w", path, err)
+	}
+	if state.PrefixSize != cp.prefixSize || state.PrefixHash != cp.prefixHash {
+		return nil, nil, false, fmt.Errorf("%w: %s", ErrCheckpointMismatch, path)
+	}
+	cp.resume = &state
+
+	if seeker == nil {
+		return input, cp, false, nil
+	}
+	if _, err = seeker.Seek(base+state.Offset, io.SeekStart); err != nil {
+		return nil, nil, false, err
+	}
+	return reader, cp, true, nil
+}
+
+// save atomically writes the checkpoint state
+func (cp *checkpointer) save(state restoreCheckpoint) error {
+	state.PrefixSize = cp.prefixSize
+	state.PrefixHash = cp.prefixHash
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := cp.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cp.path)
+}
+
+// remove deletes the checkpoint once the restore completed
+func (cp *checkpointer) remove() error {
+	if err := os.Remove(cp.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
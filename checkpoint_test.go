@@ -0,0 +1,91 @@
+package mysqldump
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_openCheckpoint(t *testing.T) {
+	const input = "SELECT 1;\nSELECT 2;\nSELECT 3;\n"
+	path := filepath.Join(t.TempDir(), "restore.checkpoint")
+
+	// new restore
+	_, cp, _, err := openCheckpoint(path, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("openCheckpoint() error = %v", err)
+	}
+	if cp.resume != nil {
+		t.Fatalf("openCheckpoint() resume = %+v, want nil", cp.resume)
+	}
+	pos := RestorePosition{Offset: 20, Statement: 2, Line: 3, Delimiter: ";"}
+	saved := restoreCheckpoint{
+		RestorePosition: pos,
+		Session:         []string{"USE `db`"},
+		Database:        "src",
+		Section:         sectionData,
+		SectionTable:    "t",
+	}
+	if err = cp.save(saved); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	// seekable input continues after the checkpoint
+	r, cp, seeked, err := openCheckpoint(path, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("openCheckpoint() error = %v", err)
+	}
+	saved.PrefixSize, saved.PrefixHash = cp.prefixSize, cp.prefixHash
+	if !seeked || cp.resume == nil || !reflect.DeepEqual(*cp.resume, saved) {
+		t.Fatalf("openCheckpoint() = %v %+v, want the saved position", seeked, cp.resume)
+	}
+	rest, _ := io.ReadAll(r)
+	if string(rest) != "SELECT 3;\n" {
+		t.Errorf("openCheckpoint() reader = %q, want %q", rest, "SELECT 3;\n")
+	}
+
+	// other inputs start from the beginning
+	r, _, seeked, err = openCheckpoint(path, io.MultiReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("openCheckpoint() error = %v", err)
+	}
+	all, _ := io.ReadAll(r)
+	if seeked || string(all) != input {
+		t.Errorf("openCheckpoint() = %v %q, want the whole input", seeked, all)
+	}
+
+	// changed input
+	if _, _, _, err = openCheckpoint(path, strings.NewReader("SELECT 4;\n")); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("openCheckpoint() error = %v, want %v", err, ErrCheckpointMismatch)
+	}
+
+	if err = cp.remove(); err != nil {
+		t.Errorf("remove() error = %v", err)
+	}
+}
+
+func Test_restorer_resume(t *testing.T) {
+	r := newDryRunRestorer("dsn_db", WithOnlyDatabases("db2"))
+	state := &restoreCheckpoint{
+		RestorePosition: RestorePosition{Offset: 100, Statement: 5, Line: 9},
+		Session:         []string{"USE `db2`"},
+		Database:        "db2",
+		Section:         sectionData,
+		SectionTable:    "t",
+	}
+	r.sc = NewStatementScanner(strings.NewReader(""))
+	if err := r.resume(state, true); err != nil {
+		t.Fatal(err)
+	}
+	if r.srcDatabase != "db2" || r.section != (section{kind: sectionData, table: "t"}) {
+		t.Fatalf("resume() database %q, section %+v, want db2 and the data of t", r.srcDatabase, r.section)
+	}
+
+	handleAll(t, r, "INSERT INTO `t` VALUES (1);\nUNLOCK TABLES;\n")
+	if got := executed(r); len(got) != 2 {
+		t.Errorf("executed = %q, want the statements of db2", got)
+	}
+}
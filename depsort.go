@@ -0,0 +1,206 @@
+package mysqldump
+
+import (
+	"sort"
+)
+
+// DependencyCycle is a group of tables referencing each other with foreign keys,
+// which WithDependencyOrder cannot order
+type DependencyCycle struct {
+	Database string
+	Tables   []string
+}
+
+// orderTables sorts the tables and views of database so that each one comes after
+// the tables it references with foreign keys and the objects its definition uses
+func (o *dumpOption) orderTables(db dumpSession, database string, tables []string) ([]string, error) {
+	deps, err := getDependencies(db, database, tables)
+	if err != nil {
+		return nil, err
+	}
+	sorted, cycles := sortByDependencies(tables, deps)
+	for _, c := range cycles {
+		o.logger.Warn("foreign key cycle", "phase", PhaseList, "db", database, "tables", c)
+		o.tracker.result.Cycles = append(o.tracker.result.Cycles, DependencyCycle{Database: database, Tables: c})
+	}
+	return sorted, nil
+}
+
+// getDependencies returns the objects of tables each table or view of database depends on
+func getDependencies(db dumpSession, database string, tables []string) (map[string][]string, error) {
+	known := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		known[t] = true
+	}
+	deps := make(map[string][]string)
+
+	rows, err := db.Query(
+		"SELECT DISTINCT TABLE_NAME, REFERENCED_TABLE_NAME FROM information_schema.KEY_COLUMN_USAGE "+
+			"WHERE TABLE_SCHEMA = ? AND REFERENCED_TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME IS NOT NULL", database, database)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var child, parent string
+		if err = rows.Scan(&child, &parent); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if known[child] && known[parent] && child != parent {
+			deps[child] = append(deps[child], parent)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// VIEW_TABLE_USAGE is missing from MariaDB and older MySQL versions,
+	// the definitions tell which objects a view uses
+	rows, err = db.Query("SELECT TABLE_NAME, VIEW_DEFINITION FROM information_schema.VIEWS WHERE TABLE_SCHEMA = ?", database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var view, definition string
+		if err = rows.Scan(&view, &definition); err != nil {
+			return nil, err
+		}
+		if !known[view] {
+			continue
+		}
+		deps[view] = append(deps[view], referencedNames(definition, database, known, view)...)
+	}
+	return deps, rows.Err()
+}
+
+// referencedNames returns the names of known found as identifiers in a view definition,
+// unqualified or qualified with database
+func referencedNames(definition, database string, known map[string]bool, self string) []string {
+	tokens := tokenize(definition, -1)
+	seen := make(map[string]bool)
+	var names []string
+	for i, tok := range tokens {
+		if tok.str || !known[tok.text] || tok.text == self || seen[tok.text] {
+			continue
+		}
+		// alias or column of a table, not a table
+		if i > 0 && (tokens[i-1].is("AS") || tokens[i-1].text == "." && (i < 2 || tokens[i-2].text != database)) {
+			continue
+		}
+		seen[tok.text] = true
+		names = append(names, tok.text)
+	}
+	return names
+}
+
+// sortByDependencies orders names so that each one comes after the names it depends on,
+// keeping the original order otherwise. Cycles are returned, and their members
+// taken in the original order.
+func sortByDependencies(names []string, deps map[string][]string) (sorted []string, cycles [][]string) {
+	pending := make(map[string]int, len(names))
+	dependents := make(map[string][]string)
+	index := make(map[string]int, len(names))
+	for i, n := range names {
+		index[n] = i
+	}
+	for _, n := range names {
+		for _, d := range deps[n] {
+			if _, ok := index[d]; ok && d != n {
+				pending[n]++
+				dependents[d] = append(dependents[d], n)
+			}
+		}
+	}
+
+	cycles = findCycles(names, deps, index)
+	inCycle := make(map[string]bool)
+	for _, c := range cycles {
+		for _, n := range c {
+			inCycle[n] = true
+		}
+	}
+
+	done := make([]bool, len(names))
+	for len(sorted) < len(names) {
+		next := -1
+		for i, n := range names {
+			if !done[i] && pending[n] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			// only cycles and their dependents are left: take the first name of a cycle
+			for i, n := range names {
+				if !done[i] && inCycle[n] {
+					next = i
+					break
+				}
+			}
+		}
+		done[next] = true
+		n := names[next]
+		sorted = append(sorted, n)
+		for _, d := range dependents[n] {
+			pending[d]--
+		}
+	}
+	return sorted, cycles
+}
+
+// findCycles returns the strongly connected components of more than one name (Tarjan)
+func findCycles(names []string, deps map[string][]string, index map[string]int) [][]string {
+	var (
+		counter int
+		stack   []string
+		cycles  [][]string
+		order   = make(map[string]int)
+		low     = make(map[string]int)
+		onStack = make(map[string]bool)
+	)
+
+	var visit func(n string)
+	visit = func(n string) {
+		counter++
+		order[n], low[n] = counter, counter
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, d := range deps[n] {
+			if _, ok := index[d]; !ok || d == n {
+				continue
+			}
+			if order[d] == 0 {
+				visit(d)
+				low[n] = min(low[n], low[d])
+			} else if onStack[d] {
+				low[n] = min(low[n], order[d])
+			}
+		}
+
+		if low[n] == order[n] {
+			var scc []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				scc = append(scc, top)
+				if top == n {
+					break
+				}
+			}
+			if len(scc) > 1 {
+				sort.Slice(scc, func(i, j int) bool { return index[scc[i]] < index[scc[j]] })
+				cycles = append(cycles, scc)
+			}
+		}
+	}
+	for _, n := range names {
+		if order[n] == 0 {
+			visit(n)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,68 @@
+package mysqldump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_sortByDependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		names      []string
+		deps       map[string][]string
+		wantSorted []string
+		wantCycles [][]string
+	}{
+		{
+			name:       "no dependencies",
+			names:      []string{"a", "b", "c"},
+			wantSorted: []string{"a", "b", "c"},
+		},
+		{
+			name:       "parents first",
+			names:      []string{"orders", "order_items", "users", "products"},
+			deps:       map[string][]string{"orders": {"users"}, "order_items": {"orders", "products"}},
+			wantSorted: []string{"users", "orders", "products", "order_items"},
+		},
+		{
+			name:       "views after what they use",
+			names:      []string{"v_top", "v_base", "t"},
+			deps:       map[string][]string{"v_top": {"v_base"}, "v_base": {"t"}},
+			wantSorted: []string{"t", "v_base", "v_top"},
+		},
+		{
+			name:       "self reference and unknown parent",
+			names:      []string{"b", "a"},
+			deps:       map[string][]string{"a": {"a"}, "b": {"other"}},
+			wantSorted: []string{"b", "a"},
+		},
+		{
+			name:       "cycle",
+			names:      []string{"c", "a", "b", "d"},
+			deps:       map[string][]string{"a": {"b"}, "b": {"a"}, "c": {"a"}},
+			wantSorted: []string{"d", "a", "c", "b"},
+			wantCycles: [][]string{{"a", "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted, cycles := sortByDependencies(tt.names, tt.deps)
+			if !reflect.DeepEqual(sorted, tt.wantSorted) {
+				t.Errorf("sorted = %v, want %v", sorted, tt.wantSorted)
+			}
+			if !reflect.DeepEqual(cycles, tt.wantCycles) {
+				t.Errorf("cycles = %v, want %v", cycles, tt.wantCycles)
+			}
+		})
+	}
+}
+
+func Test_referencedNames(t *testing.T) {
+	known := map[string]bool{"users": true, "orders": true, "v": true, "id": true}
+	definition := "select `test`.`users`.`id` AS `id`,`o`.`id` AS `oid` from (`test`.`users` join `test`.`orders` `o`)"
+	got := referencedNames(definition, "test", known, "v")
+	want := []string{"users", "orders"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("referencedNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,190 @@
+package mysqldump
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// DumpEventKind tells what a DumpEvent reports
+type DumpEventKind int
+
+const (
+	// DumpDatabaseStart is sent before the tables of a database are dumped
+	DumpDatabaseStart DumpEventKind = iota
+	// DumpTableStart is sent before a table or view is dumped
+	DumpTableStart
+	// DumpTableRows is sent about every second while the rows of a table are written
+	DumpTableRows
+	// DumpTableEnd is sent once a table or view is dumped
+	DumpTableEnd
+)
+
+func (k DumpEventKind) String() string {
+	switch k {
+	case DumpDatabaseStart:
+		return "database start"
+	case DumpTableStart:
+		return "table start"
+	case DumpTableRows:
+		return "table rows"
+	case DumpTableEnd:
+		return "table end"
+	}
+	return "unknown"
+}
+
+type (
+	// DumpEvent is reported by WithDumpProgress while Dump runs
+	DumpEvent struct {
+		Kind     DumpEventKind
+		Database string
+		// Table is empty for DumpDatabaseStart
+		Table string
+		// EstimatedRows of the table from information_schema.TABLES
+		EstimatedRows int64
+		// Rows and bytes of the table written so far
+		Rows  int64
+		Bytes int64
+		// TotalBytes written by the dump so far, before compression
+		TotalBytes int64
+		Elapsed    time.Duration
+	}
+
+	// DumpResult summarizes a Dump run
+	DumpResult struct {
+		Tables []TableResult
+		// Skipped objects, e.g. of an unsupported type
+		Skipped []SkippedObject
+		// Cycles of foreign keys found by WithDependencyOrder
+		Cycles []DependencyCycle
+		// Bytes written, before compression
+		Bytes    int64
+		Duration time.Duration
+	}
+
+	// TableResult describes a dumped table or view
+	TableResult struct {
+		Database string
+		Table    string
+		// Type is TABLE or VIEW
+		Type          string
+		Rows          int64
+		EstimatedRows int64
+		Bytes         int64
+		Duration      time.Duration
+	}
+
+	// SkippedObject is an object Dump did not export
+	SkippedObject struct {
+		Database string
+		Name     string
+		Reason   string
+	}
+)
+
+// dumpTracker builds the DumpResult and reports DumpEvents
+type dumpTracker struct {
+	fn     func(DumpEvent)
+	result *DumpResult
+	start  time.Time
+
+	// bytes written are counted before buf
+	counter *countingWriter
+	buf     *bufio.Writer
+
+	// table being dumped
+	table      *TableResult
+	tableStart time.Time
+	tableBytes int64
+	last       time.Time
+}
+
+func newDumpTracker(fn func(DumpEvent), start time.Time, w io.Writer) *dumpTracker {
+	counter := &countingWriter{w: w}
+	return &dumpTracker{
+		fn:      fn,
+		result:  &DumpResult{},
+		start:   start,
+		counter: counter,
+		buf:     bufio.NewWriter(counter),
+	}
+}
+
+// written returns the number of bytes written so far, including the buffered ones
+func (t *dumpTracker) written() int64 {
+	return t.counter.n + int64(t.buf.Buffered())
+}
+
+func (t *dumpTracker) emit(e DumpEvent) {
+	if t.fn == nil {
+		return
+	}
+	e.TotalBytes = t.written()
+	e.Elapsed = time.Since(t.start)
+	t.fn(e)
+}
+
+func (t *dumpTracker) startDatabase(database string) {
+	t.emit(DumpEvent{Kind: DumpDatabaseStart, Database: database})
+}
+
+func (t *dumpTracker) startTable(database, table, typ string, estimatedRows int64) {
+	t.table = &TableResult{Database: database, Table: table, Type: typ, EstimatedRows: estimatedRows}
+	t.tableStart = time.Now()
+	t.tableBytes = t.written()
+	t.last = t.tableStart
+	t.emit(t.event(DumpTableStart))
+}
+
+// rows records the rows written so far in the current table
+func (t *dumpTracker) rows(n int64) {
+	t.table.Rows = n
+	if now := time.Now(); now.Sub(t.last) >= progressInterval {
+		t.last = now
+		t.emit(t.event(DumpTableRows))
+	}
+}
+
+func (t *dumpTracker) endTable() {
+	t.table.Bytes = t.written() - t.tableBytes
+	t.table.Duration = time.Since(t.tableStart)
+	t.result.Tables = append(t.result.Tables, *t.table)
+	t.emit(t.event(DumpTableEnd))
+	t.table = nil
+}
+
+func (t *dumpTracker) event(kind DumpEventKind) DumpEvent {
+	return DumpEvent{
+		Kind:          kind,
+		Database:      t.table.Database,
+		Table:         t.table.Table,
+		EstimatedRows: t.table.EstimatedRows,
+		Rows:          t.table.Rows,
+		Bytes:         t.written() - t.tableBytes,
+	}
+}
+
+func (t *dumpTracker) skip(database, name, reason string) {
+	t.result.Skipped = append(t.result.Skipped, SkippedObject{Database: database, Name: name, Reason: reason})
+}
+
+// finish flushes the output and completes the result
+func (t *dumpTracker) finish() error {
+	err := t.buf.Flush()
+	t.result.Bytes = t.counter.n
+	t.result.Duration = time.Since(t.start)
+	return err
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
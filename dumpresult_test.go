@@ -0,0 +1,47 @@
+package mysqldump
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_dumpTracker(t *testing.T) {
+	var (
+		out    bytes.Buffer
+		events []DumpEventKind
+	)
+	tr := newDumpTracker(func(e DumpEvent) {
+		events = append(events, e.Kind)
+	}, time.Now(), &out)
+
+	tr.buf.WriteString("-- header\n")
+	tr.startDatabase("db")
+	tr.startTable("db", "t", "TABLE", 10)
+	tr.buf.WriteString("INSERT INTO `t` VALUES (1);\n")
+	tr.rows(1)
+	tr.endTable()
+	tr.skip("db", "s", "unsupported table type \"SEQUENCE\"")
+	if err := tr.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantEvents := []DumpEventKind{DumpDatabaseStart, DumpTableStart, DumpTableEnd}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("events = %v, want %v", events, wantEvents)
+	}
+	if tr.result.Bytes != int64(out.Len()) {
+		t.Errorf("Bytes = %d, want %d", tr.result.Bytes, out.Len())
+	}
+	if len(tr.result.Tables) != 1 {
+		t.Fatalf("Tables = %+v, want 1 table", tr.result.Tables)
+	}
+	table := tr.result.Tables[0]
+	if table.Rows != 1 || table.EstimatedRows != 10 || table.Bytes != 28 {
+		t.Errorf("Tables[0] = %+v, want 1 row, 10 estimated, 28 bytes", table)
+	}
+	if len(tr.result.Skipped) != 1 || tr.result.Skipped[0].Name != "s" {
+		t.Errorf("Skipped = %+v, want s", tr.result.Skipped)
+	}
+}
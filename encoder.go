@@ -0,0 +1,284 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultSQLMode is the sql_mode the dump header sets and Source starts with;
+	// string literals are escaped accordingly
+	defaultSQLMode     = "NO_AUTO_VALUE_ON_ZERO"
+	noBackslashEscapes = "NO_BACKSLASH_ESCAPES"
+
+	dateLayout     = "2006-01-02"
+	datetimeLayout = "2006-01-02 15:04:05"
+	zeroDate       = "0000-00-00"
+	zeroDatetime   = "0000-00-00 00:00:00"
+)
+
+// columnFsp returns the fractional seconds precision declared
+// for a DATETIME, TIMESTAMP or TIME column (e.g. 6 for DATETIME(6))
+func columnFsp(ct *sql.ColumnType) int {
+	_, scale, ok := ct.DecimalSize()
+	if !ok || scale < 0 || scale > 6 {
+		return 0
+	}
+	return int(scale)
+}
+
+// encodeTemporal encodes DATE, DATETIME, TIMESTAMP and TIME values.
+//
+// With parseTime=true the driver returns time.Time for date columns,
+// otherwise they arrive as []byte in the server text format,
+// which already carries the declared fractional part.
+func encodeTemporal(col interface{}, typ string, fsp int) (string, error) {
+	switch v := col.(type) {
+	case time.Time:
+		if typ == "TIME" {
+			break
+		}
+		return "'" + formatTime(v, typ, fsp) + "'", nil
+	case []byte:
+		if !isTemporalLiteral(v) {
+			return "", fmt.Errorf("invalid %s value %q", typ, v)
+		}
+		return "'" + string(v) + "'", nil
+	case string:
+		if !isTemporalLiteral([]byte(v)) {
+			return "", fmt.Errorf("invalid %s value %q", typ, v)
+		}
+		return "'" + v + "'", nil
+	}
+	return "", fmt.Errorf("cannot encode %T as %s", col, typ)
+}
+
+// formatTime formats t for typ, keeping fsp fractional digits.
+// Zero dates ('0000-00-00') are parsed by the driver into time.Time{}.
+func formatTime(t time.Time, typ string, fsp int) string {
+	if typ == "DATE" {
+		if t.IsZero() {
+			return zeroDate
+		}
+		return t.Format(dateLayout)
+	}
+
+	layout := datetimeLayout
+	if fsp > 0 {
+		layout += "." + strings.Repeat("0", fsp)
+	}
+	if t.IsZero() {
+		return zeroDatetime + layout[len(datetimeLayout):]
+	}
+	return t.Format(layout)
+}
+
+// isTemporalLiteral reports whether b only holds characters
+// the server uses to print date and time values
+func isTemporalLiteral(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '-', c == ':', c == ' ', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// columnScale returns the declared number of decimals of a FLOAT or DOUBLE
+// column (e.g. 2 for FLOAT(8,2)), or -1 when none was declared
+func columnScale(ct *sql.ColumnType) int {
+	_, scale, ok := ct.DecimalSize()
+	if !ok || scale < 0 || scale > 30 {
+		return -1
+	}
+	return int(scale)
+}
+
+// encodeNumeric encodes integer, floating point and fixed point values
+// so that they read back exactly the same.
+//
+// Integers and floats are converted by the driver to int64, uint64 (BIGINT UNSIGNED),
+// float32 (FLOAT) or float64 (DOUBLE); anything else arrives as []byte.
+func encodeNumeric(col interface{}, typ string, scale int) (string, error) {
+	switch v := col.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float32:
+		return formatFloat(float64(v), scale, 32)
+	case float64:
+		return formatFloat(v, scale, 64)
+	case []byte:
+		if !isNumericLiteral(v) {
+			return "", fmt.Errorf("invalid %s value %q", typ, v)
+		}
+		return string(v), nil
+	}
+	return "", fmt.Errorf("cannot encode %T as %s", col, typ)
+}
+
+// formatFloat formats f with the declared scale, or with the shortest
+// representation that round-trips to the same bitSize value.
+// Negative zero is written as a float literal since -0 would be read as an integer.
+func formatFloat(f float64, scale, bitSize int) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("cannot encode %v", f)
+	}
+	if f == 0 && math.Signbit(f) {
+		return "-0E0", nil
+	}
+	if scale >= 0 {
+		return strconv.FormatFloat(f, 'f', scale, bitSize), nil
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize), nil
+}
+
+// isNumericLiteral reports whether b is a plain decimal or scientific number
+func isNumericLiteral(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for i, c := range b {
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.':
+		case c == '-' || c == '+':
+			if i > 0 && b[i-1] != 'e' && b[i-1] != 'E' {
+				return false
+			}
+		case c == 'e' || c == 'E':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// literalEncoder escapes string literals for the sql_mode
+// and connection character set the dump is restored with
+type literalEncoder struct {
+	// NO_BACKSLASH_ESCAPES is set: quotes are doubled and backslash is an ordinary character
+	noBackslashEscapes bool
+	// character set the values are read and written in
+	charset string
+}
+
+// quote returns b as a single quoted string literal
+func (e literalEncoder) quote(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b) + 2)
+	sb.WriteByte('\'')
+
+	if e.noBackslashEscapes {
+		for _, c := range b {
+			if c == '\'' {
+				sb.WriteByte('\'')
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteByte('\'')
+		return sb.String()
+	}
+
+	for i := 0; i < len(b); i++ {
+		// copy multibyte characters as is, their trailing byte may be 0x5C ('\\')
+		if n := mbCharLen(e.charset, b[i:]); n > 1 {
+			sb.Write(b[i : i+n])
+			i += n - 1
+			continue
+		}
+		switch c := b[i]; c {
+		case 0:
+			sb.WriteString("\\0")
+		case '\n':
+			sb.WriteString("\\n")
+		case '\r':
+			sb.WriteString("\\r")
+		case '\\':
+			sb.WriteString("\\\\")
+		case '\'':
+			sb.WriteString("\\'")
+		case '"':
+			sb.WriteString("\\\"")
+		case '\x1A': // ASCII 26, Ctrl+Z
+			sb.WriteString("\\Z")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
+// mbCharLen returns the length of the multibyte character at the start of b
+// for the character sets whose trailing bytes may collide with ASCII, or 1.
+func mbCharLen(charset string, b []byte) int {
+	if len(b) < 2 || b[0] < 0x81 {
+		return 1
+	}
+	lead, trail := b[0], b[1]
+
+	switch charset {
+	case "big5", "gbk":
+		if lead <= 0xFE && trail >= 0x40 && trail <= 0xFE {
+			return 2
+		}
+	case "sjis", "cp932":
+		if (lead <= 0x9F || lead >= 0xE0 && lead <= 0xFC) && trail >= 0x40 && trail <= 0xFC {
+			return 2
+		}
+	case "gb18030":
+		if lead > 0xFE {
+			return 1
+		}
+		if trail >= 0x30 && trail <= 0x39 {
+			if len(b) >= 4 {
+				return 4
+			}
+			return 1
+		}
+		if trail >= 0x40 && trail <= 0xFE {
+			return 2
+		}
+	}
+	return 1
+}
+
+// encodeString encodes character, ENUM, SET and JSON values
+func (e literalEncoder) encodeString(col interface{}, typ string) (string, error) {
+	switch v := col.(type) {
+	case []byte:
+		return e.quote(v), nil
+	case string:
+		return e.quote([]byte(v)), nil
+	}
+	return "", fmt.Errorf("cannot encode %T as %s", col, typ)
+}
+
+// encodeBinary encodes BIT and binary string values as hexadecimal literals
+func encodeBinary(col interface{}, typ string) (string, error) {
+	v, ok := col.([]byte)
+	if !ok {
+		return "", fmt.Errorf("cannot encode %T as %s", col, typ)
+	}
+	if len(v) == 0 {
+		return "''", nil
+	}
+	return fmt.Sprintf("0x%X", v), nil
+}
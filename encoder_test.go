@@ -0,0 +1,207 @@
+package mysqldump
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func Test_encodeTemporal(t *testing.T) {
+	type args struct {
+		col interface{}
+		typ string
+		fsp int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "date parseTime",
+			args: args{col: time.Date(2023, 3, 17, 0, 0, 0, 0, time.UTC), typ: "DATE"},
+			want: "'2023-03-17'",
+		},
+		{
+			name: "datetime(6) parseTime",
+			args: args{col: time.Date(2023, 3, 17, 10, 0, 0, 123456000, time.UTC), typ: "DATETIME", fsp: 6},
+			want: "'2023-03-17 10:00:00.123456'",
+		},
+		{
+			name: "timestamp(3) keeps trailing zeros",
+			args: args{col: time.Date(2023, 3, 17, 10, 0, 0, 100000000, time.UTC), typ: "TIMESTAMP", fsp: 3},
+			want: "'2023-03-17 10:00:00.100'",
+		},
+		{
+			name: "zero datetime parseTime",
+			args: args{col: time.Time{}, typ: "DATETIME", fsp: 2},
+			want: "'0000-00-00 00:00:00.00'",
+		},
+		{
+			name: "zero date parseTime",
+			args: args{col: time.Time{}, typ: "DATE"},
+			want: "'0000-00-00'",
+		},
+		{
+			name: "datetime(6) without parseTime",
+			args: args{col: []byte("2023-03-17 10:00:00.000001"), typ: "DATETIME", fsp: 6},
+			want: "'2023-03-17 10:00:00.000001'",
+		},
+		{
+			name: "negative time",
+			args: args{col: []byte("-838:59:59"), typ: "TIME"},
+			want: "'-838:59:59'",
+		},
+		{
+			name:    "mismatched type",
+			args:    args{col: int64(1), typ: "DATETIME"},
+			wantErr: true,
+		},
+		{
+			name:    "garbage bytes",
+			args:    args{col: []byte("2023'; DROP"), typ: "DATE"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeTemporal(tt.args.col, tt.args.typ, tt.args.fsp)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encodeTemporal() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("encodeTemporal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_encodeNumeric(t *testing.T) {
+	type args struct {
+		col   interface{}
+		typ   string
+		scale int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "min bigint",
+			args: args{col: int64(math.MinInt64), typ: "BIGINT", scale: -1},
+			want: "-9223372036854775808",
+		},
+		{
+			name: "max bigint unsigned",
+			args: args{col: uint64(math.MaxUint64), typ: "BIGINT", scale: -1},
+			want: "18446744073709551615",
+		},
+		{
+			name: "bigint unsigned as bytes",
+			args: args{col: []byte("18446744073709551615"), typ: "BIGINT", scale: -1},
+			want: "18446744073709551615",
+		},
+		{
+			name: "decimal",
+			args: args{col: []byte("-1234.56"), typ: "DECIMAL", scale: -1},
+			want: "-1234.56",
+		},
+		{
+			name: "float keeps shortest representation",
+			args: args{col: float32(0.1), typ: "FLOAT", scale: -1},
+			want: "0.1",
+		},
+		{
+			name: "double keeps all digits",
+			args: args{col: 0.1234567890123, typ: "DOUBLE", scale: -1},
+			want: "0.1234567890123",
+		},
+		{
+			name: "largest double",
+			args: args{col: math.MaxFloat64, typ: "DOUBLE", scale: -1},
+			want: "1.7976931348623157e+308",
+		},
+		{
+			name: "tiny double",
+			args: args{col: 5e-324, typ: "DOUBLE", scale: -1},
+			want: "5e-324",
+		},
+		{
+			name: "negative zero",
+			args: args{col: math.Copysign(0, -1), typ: "DOUBLE", scale: -1},
+			want: "-0E0",
+		},
+		{
+			name: "precision declared float",
+			args: args{col: float32(1234.5), typ: "FLOAT", scale: 2},
+			want: "1234.50",
+		},
+		{
+			name:    "mismatched type",
+			args:    args{col: "abc", typ: "INT", scale: -1},
+			wantErr: true,
+		},
+		{
+			name:    "garbage bytes",
+			args:    args{col: []byte("1 OR 1=1"), typ: "DECIMAL", scale: -1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeNumeric(tt.args.col, tt.args.typ, tt.args.scale)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encodeNumeric() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("encodeNumeric() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_literalEncoder_quote(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  literalEncoder
+		in   string
+		want string
+	}{
+		{
+			name: "backslash escapes",
+			enc:  literalEncoder{charset: "utf8mb4"},
+			in:   "it's a \\ \"path\"\n\x00\x1A",
+			want: `'it\'s a \\ \"path\"\n\0\Z'`,
+		},
+		{
+			name: "no backslash escapes",
+			enc:  literalEncoder{noBackslashEscapes: true, charset: "utf8mb4"},
+			in:   "it's a \\ path\n",
+			want: "'it''s a \\ path\n'",
+		},
+		{
+			name: "gbk trailing backslash byte is not escaped",
+			enc:  literalEncoder{charset: "gbk"},
+			in:   "\xbf\x5c'",
+			want: "'\xbf\x5c\\''",
+		},
+		{
+			name: "utf8mb4 multibyte",
+			enc:  literalEncoder{charset: "utf8mb4"},
+			in:   "ação\\",
+			want: `'ação\\'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.enc.quote([]byte(tt.in)); got != tt.want {
+				t.Errorf("quote() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedType is returned when Dump meets a column type it cannot export
+	ErrUnsupportedType = errors.New("unsupported column type")
+	// ErrWriterNotFile is returned when compression is enabled but the writer is not an *os.File
+	ErrWriterNotFile = errors.New("writer is not a file")
+	// ErrShadowForeignKey is returned when WithShadowRestore meets a table with foreign keys,
+	// or a live table referenced by one: constraint names are unique per database
+	// and references would follow the renamed tables
+	ErrShadowForeignKey = errors.New("WithShadowRestore does not support foreign keys")
+)
+
+// Phase is the step of a Dump or Source an error happened in
+type Phase string
+
+const (
+	PhaseConnect  Phase = "connect"
+	PhaseHeader   Phase = "header"
+	PhaseFooter   Phase = "footer"
+	PhaseList     Phase = "list"
+	PhaseSchema   Phase = "schema"
+	PhaseData     Phase = "data"
+	PhaseTrigger  Phase = "trigger"
+	PhaseView     Phase = "view"
+	PhaseCompress Phase = "compress"
+
+	PhaseSession    Phase = "session"
+	PhaseCheckpoint Phase = "checkpoint"
+	PhaseRead       Phase = "read"
+	PhaseRewrite    Phase = "rewrite"
+	PhaseExec       Phase = "exec"
+	PhaseCommit     Phase = "commit"
+)
+
+type (
+	// DumpError is returned by Dump with the object and step that failed
+	DumpError struct {
+		Phase    Phase
+		Database string
+		Table    string
+		Column   string
+		// Offset is the number of bytes written when the error happened
+		Offset int64
+		Err    error
+	}
+
+	// SourceError is returned by Source with the statement and step that failed
+	SourceError struct {
+		Phase    Phase
+		Database string
+		Table    string
+		// Offset and Line of the statement in the input
+		Offset int64
+		Line   int
+		Err    error
+	}
+)
+
+func (e *DumpError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("dump " + string(e.Phase))
+	if name := objectName(e.Database, e.Table); name != "" {
+		sb.WriteString(" " + name)
+	}
+	if e.Column != "" {
+		sb.WriteString(" column " + e.Column)
+	}
+	sb.WriteString(": " + e.Err.Error())
+	return sb.String()
+}
+
+func (e *DumpError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SourceError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("source " + string(e.Phase))
+	if name := objectName(e.Database, e.Table); name != "" {
+		sb.WriteString(" " + name)
+	}
+	if e.Line > 0 {
+		sb.WriteString(fmt.Sprintf(" at line %d, offset %d", e.Line, e.Offset))
+	}
+	sb.WriteString(": " + e.Err.Error())
+	return sb.String()
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// objectName formats database.table, either may be empty
+func objectName(database, table string) string {
+	switch {
+	case database == "":
+		return table
+	case table == "":
+		return database
+	}
+	return database + "." + table
+}
+
+// dumpError wraps err with the step and object of the dump that failed,
+// completing the context of a DumpError returned deeper
+func (o *dumpOption) dumpError(phase Phase, database, table string, err error) error {
+	var offset int64
+	if o.tracker != nil {
+		offset = o.tracker.written()
+	}
+	var de *DumpError
+	if errors.As(err, &de) {
+		if de.Phase == "" {
+			de.Phase = phase
+		}
+		if de.Database == "" {
+			de.Database = database
+		}
+		if de.Table == "" {
+			de.Table = table
+		}
+		if de.Offset == 0 {
+			de.Offset = offset
+		}
+		return err
+	}
+	return &DumpError{Phase: phase, Database: database, Table: table, Offset: offset, Err: err}
+}
+
+// sourceError wraps err with the step of the restore that failed,
+// unless it already is a SourceError
+func sourceError(phase Phase, err error) error {
+	var se *SourceError
+	if err == nil || errors.As(err, &se) {
+		return err
+	}
+	return &SourceError{Phase: phase, Err: err}
+}
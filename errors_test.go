@@ -0,0 +1,51 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func Test_dumpError(t *testing.T) {
+	var o dumpOption
+
+	inner := &DumpError{Phase: PhaseData, Column: "c", Err: fmt.Errorf("%w BOOL", ErrUnsupportedType)}
+	err := o.dumpError(PhaseData, "db", "t", inner)
+
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("errors.Is(%v, ErrUnsupportedType) = false", err)
+	}
+	var de *DumpError
+	if !errors.As(err, &de) {
+		t.Fatalf("errors.As(%v, *DumpError) = false", err)
+	}
+	if de.Database != "db" || de.Table != "t" || de.Column != "c" {
+		t.Errorf("DumpError = %+v, want db.t column c", de)
+	}
+	if want := "dump data db.t column c: unsupported column type BOOL"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	err = o.dumpError(PhaseCompress, "", "", ErrWriterNotFile)
+	if want := "dump compress: writer is not a file"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func Test_sourceError(t *testing.T) {
+	myErr := &mysql.MySQLError{Number: 1146, Message: "Table 'db.t' doesn't exist"}
+	var err error = &SourceError{Phase: PhaseExec, Database: "db", Table: "t", Offset: 42, Line: 3, Err: myErr}
+
+	if want := "source exec db.t at line 3, offset 42: Error 1146: Table 'db.t' doesn't exist"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	var got *mysql.MySQLError
+	if !errors.As(err, &got) || got.Number != 1146 {
+		t.Errorf("errors.As(%v, *mysql.MySQLError) = false", err)
+	}
+	if sourceError(PhaseCommit, err) != err {
+		t.Errorf("sourceError() wrapped a SourceError again")
+	}
+}
@@ -12,7 +12,7 @@ func main() {
 
 	f, _ := os.Create("dump.sql")
 
-	_ = mysqldump.Dump(
+	_, _ = mysqldump.Dump(
 		dsn,                          // DSN
 		mysqldump.WithDropTable(),    // Option: Delete table before create (Default: Not delete table)
 		mysqldump.WithData(),         // Option: Dump Data (Default: Only dump table schema)
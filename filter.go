@@ -0,0 +1,76 @@
+package mysqldump
+
+import "strings"
+
+// restoreFilter selects the databases and tables restored by Source.
+// Tables are given by name, or as "database.table" to match a single database.
+type restoreFilter struct {
+	databases  map[string]bool
+	tables     map[string]bool
+	skipTables map[string]bool
+}
+
+func (f *restoreFilter) active() bool {
+	return len(f.databases) > 0 || len(f.tables) > 0 || len(f.skipTables) > 0
+}
+
+// database reports whether the statements of database are restored
+func (f *restoreFilter) database(database string) bool {
+	return len(f.databases) == 0 || f.databases[database]
+}
+
+// table reports whether table of database is restored
+func (f *restoreFilter) table(database, table string) bool {
+	if !f.database(database) {
+		return false
+	}
+	if matchTable(f.skipTables, database, table) {
+		return false
+	}
+	return len(f.tables) == 0 || matchTable(f.tables, database, table)
+}
+
+func matchTable(names map[string]bool, database, table string) bool {
+	return names[table] || names[database+"."+table]
+}
+
+func toSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.TrimSpace(name)] = true
+	}
+	return set
+}
+
+// selected reports whether stmt is part of the restored objects.
+// Statements outside of any table section, such as the session settings
+// of the dump header, are always run on the selected databases.
+func (r *restorer) selected(info statementInfo) bool {
+	f := &r.o.filter
+	if !f.active() {
+		return true
+	}
+
+	switch {
+	case info.verb == "USE":
+		return f.database(info.database)
+	case info.object == "DATABASE" || info.object == "SCHEMA":
+		return f.database(info.database)
+	}
+
+	database := r.srcDatabase
+	if info.database != "" {
+		database = info.database
+	}
+	if r.section.kind != sectionNone {
+		return f.table(database, r.section.table)
+	}
+	if info.table != "" {
+		return f.table(database, info.table)
+	}
+	switch info.verb {
+	case "SET", "UNLOCK", "COMMIT", "START", "BEGIN", "ROLLBACK":
+		return true
+	}
+	return f.database(database)
+}
@@ -0,0 +1,45 @@
+package mysqldump
+
+import "testing"
+
+func Test_restorer_selected(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []SourceOption
+		database string
+		section  section
+		text     string
+		want     bool
+	}{
+		{name: "no filter", database: "db2", text: "INSERT INTO `t` VALUES (1)", want: true},
+
+		{name: "use selected database", opts: []SourceOption{WithOnlyDatabases("db1")}, text: "USE `db1`", want: true},
+		{name: "use other database", opts: []SourceOption{WithOnlyDatabases("db1")}, text: "USE `db2`", want: false},
+		{name: "create other database", opts: []SourceOption{WithOnlyDatabases("db1")}, text: "CREATE DATABASE IF NOT EXISTS `db2`", want: false},
+		{name: "statement in other database", opts: []SourceOption{WithOnlyDatabases("db1")}, database: "db2", text: "INSERT INTO `t` VALUES (1)", want: false},
+		{name: "qualified name in selected database", opts: []SourceOption{WithOnlyDatabases("db1")}, database: "db2", text: "INSERT INTO `db1`.`t` VALUES (1)", want: true},
+		{name: "session settings", opts: []SourceOption{WithOnlyDatabases("db1")}, database: "db2", text: "SET NAMES utf8mb4", want: true},
+		{name: "routine in other database", opts: []SourceOption{WithOnlyDatabases("db1")}, database: "db2", text: "CREATE PROCEDURE p() SELECT 1", want: false},
+
+		{name: "section of selected table", opts: []SourceOption{WithOnlyTables("t")}, database: "db", section: section{kind: sectionStructure, table: "t"}, text: "DROP TABLE IF EXISTS `t`", want: true},
+		{name: "section of other table", opts: []SourceOption{WithOnlyTables("t")}, database: "db", section: section{kind: sectionData, table: "u"}, text: "LOCK TABLES `u` WRITE", want: false},
+		{name: "section wins over statement", opts: []SourceOption{WithOnlyTables("t")}, database: "db", section: section{kind: sectionTrigger, table: "u"}, text: "CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1", want: false},
+		{name: "statement outside of sections", opts: []SourceOption{WithOnlyTables("t")}, database: "db", text: "INSERT INTO `t` VALUES (1)", want: true},
+		{name: "qualified table", opts: []SourceOption{WithOnlyTables("db2.u")}, database: "db1", text: "INSERT INTO `db2`.`u` VALUES (1)", want: true},
+		{name: "qualified table in other database", opts: []SourceOption{WithOnlyTables("db2.u")}, database: "db1", text: "INSERT INTO `u` VALUES (1)", want: false},
+		{name: "commit", opts: []SourceOption{WithOnlyTables("t")}, database: "db", text: "COMMIT", want: true},
+
+		{name: "skipped table", opts: []SourceOption{WithSkipTables("t")}, database: "db", section: section{kind: sectionData, table: "t"}, text: "INSERT INTO `t` VALUES (1)", want: false},
+		{name: "not skipped table", opts: []SourceOption{WithSkipTables("t")}, database: "db", section: section{kind: sectionData, table: "u"}, text: "INSERT INTO `u` VALUES (1)", want: true},
+		{name: "skipped and selected", opts: []SourceOption{WithOnlyTables("t", "u"), WithSkipTables("db.u")}, database: "db", text: "DROP TABLE `u`", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newDryRunRestorer(tt.database, tt.opts...)
+			r.section = tt.section
+			if got := r.selected(parseStatement(tt.text)); got != tt.want {
+				t.Errorf("selected(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package mysqldump
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// discardHandler drops every record, the library is silent unless given a logger
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// newLogger returns the logger to use when none was given:
+// the debug logger with WithDebug, slog.Default with WithLogErrors, a silent one otherwise
+func newLogger(debug, logErrors bool) *slog.Logger {
+	switch {
+	case debug:
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	case logErrors:
+		return slog.Default()
+	}
+	return slog.New(discardHandler{})
+}
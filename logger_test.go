@@ -0,0 +1,29 @@
+package mysqldump
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func Test_newLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		debug     bool
+		logErrors bool
+		level     slog.Level
+		want      bool
+	}{
+		{name: "silent by default", level: slog.LevelError, want: false},
+		{name: "log errors", logErrors: true, level: slog.LevelError, want: true},
+		{name: "debug", debug: true, level: slog.LevelDebug, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLogger(tt.debug, tt.logErrors)
+			if got := l.Enabled(context.Background(), tt.level); got != tt.want {
+				t.Errorf("Enabled(%s) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
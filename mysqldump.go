@@ -2,10 +2,11 @@ package mysqldump
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -14,10 +15,6 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
-func init() {
-	log.SetFlags(log.Lshortfile | log.LstdFlags)
-}
-
 type (
 	dumpOption struct {
 		Host string
@@ -27,6 +24,10 @@ type (
 		Startime time.Time
 		// Client version
 		Version string
+		// sql_mode set by the dump header
+		SQLMode string
+		// Character set of the dumped values, set by the dump header
+		Charset string
 
 		//Export table data
 		isData bool
@@ -36,142 +37,244 @@ type (
 		tables []string
 		// Export all tables
 		isAllTables bool
+		// Order tables by foreign keys and views by the objects they use
+		dependencyOrder bool
 		// Whether to delete the table
 		isDropTable bool
+		// Replace the DEFINER of views, removed when viewDefiner is empty
+		isViewDefiner bool
+		viewDefiner   string
+		// Replace the SQL SECURITY of views, DEFINER or INVOKER
+		viewSQLSecurity string
 		// Whether to add a library selection script. When exporting multiple libraries, this setting is enabled by default.
 		isUseDb bool
 		// Batch insert to improve export efficiency
 		perDataNumber int
 		// writer defaults to os.Stdout
 		writer io.Writer
-		// Whether to output debug logs
+		// Log errors with slog.Default when no logger is given
 		log bool
+		// logger of the dump, silent by default
+		logger *slog.Logger
 		// Whether to compress the output with gzip
 		// only works if the Writer stream is a file
 		isCompressed     bool
 		compressionLevel int
-	}
-	triggerStruct struct {
-		Trigger   string
-		Event     string
-		Table     string
-		Statement string
-		Timing    string
+		// Escape string literals for NO_BACKSLASH_ESCAPES
+		noBackslashEscapes bool
+		// encoder for string literals, matching SQLMode and Charset
+		enc literalEncoder
+		// called with the progress of the dump
+		progress func(DumpEvent)
+		// builds the DumpResult
+		tracker *dumpTracker
+		// names of the triggers of each table, per database
+		triggers map[string]map[string][]string
 	}
 	DumpOption func(*dumpOption)
 )
 
-var (
-	dpOpt       dumpOption
-	allTriggers map[string][]triggerStruct
-)
-
 // Dump exports DB contents from MySQL/MariaDB to a writer source (file, stdOut, etc.)
-// nolint: gocyclo
-func Dump(dsn string, opts ...DumpOption) (err error) {
-	if err = dpOpt.dump(dsn, opts...); err != nil {
+// The returned result summarizes the tables dumped and the objects skipped.
+func Dump(dsn string, opts ...DumpOption) (*DumpResult, error) {
+	o := newDumpOption(opts)
+
+	// parse DSN options
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		o.logger.Error("invalid DSN", "phase", PhaseConnect, "err", err)
+		return nil, o.dumpError(PhaseConnect, "", "", err)
+	}
+
+	// open connection to Client
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		o.logger.Error("cannot open the database", "phase", PhaseConnect, "err", err)
+		return nil, o.dumpError(PhaseConnect, "", "", err)
+	}
+	defer db.Close()
+
+	// get database host
+	o.Host = cfg.Addr
+	return o.run(context.Background(), db, cfg.DBName)
+}
+
+// DumpDB exports DB contents like Dump, from an existing connection pool (*sql.DB),
+// connection (*sql.Conn) or any Querier, e.g. a transaction giving a consistent snapshot.
+// Without WithDBs the current database is exported. A connection taken from a pool is closed
+// afterwards rather than given back, any other Querier is left on its current database.
+func DumpDB(ctx context.Context, db Querier, opts ...DumpOption) (*DumpResult, error) {
+	o := newDumpOption(opts)
+	return o.run(ctx, db, "")
+}
+
+func newDumpOption(opts []DumpOption) *dumpOption {
+	o := &dumpOption{}
+	// iterate over existing plugins (With...)
+	// and execute it
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.logger == nil {
+		o.logger = newLogger(false, o.log)
+	}
+	return o
+}
+
+// run dumps db, then compresses the output if enabled
+func (o *dumpOption) run(ctx context.Context, db Querier, dbName string) (result *DumpResult, err error) {
+	if result, err = o.dump(ctx, db, dbName); err != nil {
 		return
 	}
 
-	if dpOpt.isCompressed {
-		if dpOpt.log {
-			log.Println("[gzip] [info] gzip compression enabled")
-		}
+	if o.isCompressed {
+		o.logger.Info("gzip compression enabled", "phase", PhaseCompress)
 
-		gz := extensions.NewGzip(dpOpt.compressionLevel)
-		switch dpOpt.writer.(type) {
+		gz := extensions.NewGzip(o.compressionLevel)
+		switch o.writer.(type) {
 		case *os.File:
-			gz.Filename = dpOpt.writer.(*os.File).Name()
+			gz.Filename = o.writer.(*os.File).Name()
 		default:
-			log.Println("[gzip] [error] writer stream is not a file!")
-			return
+			o.logger.Error("writer stream is not a file", "phase", PhaseCompress)
+			return result, o.dumpError(PhaseCompress, "", "", ErrWriterNotFile)
 		}
 
 		if err = gz.Compress(); err != nil {
-			log.Printf("[gzip] [error] %v \n", err)
-			return
+			o.logger.Error("compression failed", "phase", PhaseCompress, "err", err)
+			return result, o.dumpError(PhaseCompress, "", "", err)
 		}
 	}
 	return
 }
 
-func (o *dumpOption) dump(dsn string, opts ...DumpOption) (err error) {
+// nolint: gocyclo
+func (o *dumpOption) dump(ctx context.Context, q Querier, dbName string) (result *DumpResult, err error) {
 	o.Startime = time.Now()
-	log.Printf("[BACKUP] [dump] started at %s\n", o.Startime.Format(DEFAULT_LOG_TIMESTAMP))
-
+	o.logger.Info("dump started")
 	defer func() {
-		end := time.Now()
-		log.Printf("[BACKUP] [dump] terminated at %s, execution time %s\n", end.Format(DEFAULT_LOG_TIMESTAMP), end.Sub(o.Startime))
+		o.logger.Info("dump finished", "duration", time.Since(o.Startime))
 	}()
 
-	// iterate over existing plugins (With...)
-	// and execute it
-	for _, opt := range opts {
-		opt(o)
+	// USE and the queries after it must run on the same connection
+	pool, pinned := q.(*sql.DB)
+	if pinned {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			o.logger.Error("cannot open a session", "phase", PhaseConnect, "err", err)
+			return nil, o.dumpError(PhaseConnect, "", "", err)
+		}
+		defer discardConn(conn)
+		q = conn
 	}
+	db := dumpSession{ctx: ctx, q: q}
 
-	// parse DSN options
-	cfg, err := parseDSN(dsn)
-	if err != nil {
-		log.Printf("[parse-dsn] [error] %v \n", err)
-		return err
+	if dbName == "" {
+		var current sql.NullString
+		if err = db.QueryRow("SELECT DATABASE()").Scan(&current); err != nil {
+			o.logger.Error("cannot read the current database", "phase", PhaseConnect, "err", err)
+			return nil, o.dumpError(PhaseConnect, "", "", err)
+		}
+		dbName = current.String
+		if !pinned && current.Valid {
+			// the session of the caller is left on its database
+			defer func() {
+				if _, err := db.Exec("USE " + quoteIdentifier(current.String)); err != nil {
+					o.logger.Warn("cannot select the database back", "phase", PhaseConnect, "db", current.String, "err", err)
+				}
+			}()
+		}
+	}
+	if o.Host == "" {
+		if err = db.QueryRow("SELECT CONCAT(@@hostname, ':', @@port)").Scan(&o.Host); err != nil {
+			o.logger.Error("cannot read the server host", "phase", PhaseConnect, "err", err)
+			return nil, o.dumpError(PhaseConnect, "", "", err)
+		}
 	}
 
 	// check if multiple DBs are selected
-	// if not then fetch the DB name from current DSN
+	// if not then export the database of the DSN or connection
 	if len(o.Dbs) == 0 {
 		o.Dbs = []string{
-			cfg.DBName,
+			dbName,
 		}
 	}
 	if len(o.tables) == 0 {
 		o.isAllTables = true
 	}
+	switch o.viewSQLSecurity {
+	case "", "DEFINER", "INVOKER":
+	default:
+		err = fmt.Errorf("invalid SQL SECURITY %q, want DEFINER or INVOKER", o.viewSQLSecurity)
+		o.logger.Error("invalid option", "phase", PhaseView, "err", err)
+		return nil, o.dumpError(PhaseView, "", "", err)
+	}
 
 	if o.writer == nil {
 		o.writer = os.Stdout
 		o.isCompressed = false
 	}
-	buf := bufio.NewWriter(o.writer)
-	defer buf.Flush()
-
-	// get database host
-	o.Host = cfg.Addr
-
-	// open connection to Client
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		if o.log {
-			log.Printf("[error] %v \n", err)
+	o.tracker = newDumpTracker(o.progress, o.Startime, o.writer)
+	buf := o.tracker.buf
+	result = o.tracker.result
+	defer func() {
+		if ferr := o.tracker.finish(); err == nil && ferr != nil {
+			err = o.dumpError(PhaseFooter, "", "", ferr)
 		}
-		return err
-	}
-	defer db.Close()
+	}()
 
 	if err = db.QueryRow("SELECT version()").Scan(&o.Version); err != nil {
-		log.Printf("[error] %v \n", err)
-		return err
+		o.logger.Error("cannot read the server version", "phase", PhaseConnect, "err", err)
+		return result, o.dumpError(PhaseConnect, "", "", err)
+	}
+
+	// values are read in character_set_results and must be restored with it
+	var charset sql.NullString
+	if err = db.QueryRow("SELECT @@character_set_results").Scan(&charset); err != nil {
+		o.logger.Error("cannot read the character set", "phase", PhaseConnect, "err", err)
+		return result, o.dumpError(PhaseConnect, "", "", err)
+	}
+	o.Charset = "utf8mb4"
+	if charset.Valid && charset.String != "" {
+		o.Charset = charset.String
+	}
+	// TIMESTAMP values are read in UTC, the time zone the header restores them in
+	if _, err = db.Exec("SET @OLD_TIME_ZONE=@@TIME_ZONE, TIME_ZONE='+00:00'"); err != nil {
+		o.logger.Error("cannot set the time zone", "phase", PhaseConnect, "err", err)
+		return result, o.dumpError(PhaseConnect, "", "", err)
+	}
+	if !pinned {
+		defer func() {
+			if _, err := db.Exec("SET TIME_ZONE=@OLD_TIME_ZONE"); err != nil {
+				o.logger.Warn("cannot set the time zone back", "phase", PhaseConnect, "err", err)
+			}
+		}()
+	}
+	o.SQLMode = defaultSQLMode
+	if o.noBackslashEscapes {
+		o.SQLMode += "," + noBackslashEscapes
+	}
+	o.enc = literalEncoder{
+		noBackslashEscapes: o.noBackslashEscapes,
+		charset:            o.Charset,
 	}
 
 	tpl, err := NewTemplate()
 	if err != nil {
-		log.Printf("[template] [error] %v \n", err)
-		return err
+		o.logger.Error("template failed", "phase", PhaseHeader, "err", err)
+		return result, o.dumpError(PhaseHeader, "", "", err)
 	}
 
 	// inject header template
 	if err := tpl.Header.Execute(buf, o); err != nil {
-		log.Printf("[header] [error] %v \n", err)
-		return err
+		o.logger.Error("header failed", "phase", PhaseHeader, "err", err)
+		return result, o.dumpError(PhaseHeader, "", "", err)
 	}
 
 	if o.isAllDB {
 		o.Dbs, err = getDBs(db)
 		if err != nil {
-			if o.log {
-				log.Printf("[error] %v \n", err)
-			}
-			return err
+			o.logger.Error("cannot list the databases", "phase", PhaseList, "err", err)
+			return result, o.dumpError(PhaseList, "", "", err)
 		}
 	}
 	if len(o.Dbs) > 1 {
@@ -179,114 +282,133 @@ func (o *dumpOption) dump(dsn string, opts ...DumpOption) (err error) {
 	}
 
 	for _, dbStr := range o.Dbs {
-		_, err = db.Exec(fmt.Sprintf("USE `%s`", dbStr))
+		o.tracker.startDatabase(dbStr)
+		_, err = db.Exec("USE " + quoteIdentifier(dbStr))
 		if err != nil {
-			if o.log {
-				log.Printf("[error] %v \n", err)
-			}
-			return err
+			o.logger.Error("cannot select the database", "phase", PhaseList, "db", dbStr, "err", err)
+			return result, o.dumpError(PhaseList, dbStr, "", err)
 		}
 
 		var tables []string
 		if o.isAllTables {
 			tmp, err := getAllTables(db)
 			if err != nil {
-				if o.log {
-					log.Printf("[error] %v \n", err)
-				}
-				return err
+				o.logger.Error("cannot list the tables", "phase", PhaseList, "db", dbStr, "err", err)
+				return result, o.dumpError(PhaseList, dbStr, "", err)
 			}
 			tables = tmp
 		} else {
 			tables = o.tables
 		}
+		if o.dependencyOrder {
+			if tables, err = o.orderTables(db, dbStr, tables); err != nil {
+				o.logger.Error("cannot read the dependencies", "phase", PhaseList, "db", dbStr, "err", err)
+				return result, o.dumpError(PhaseList, dbStr, "", err)
+			}
+		}
 		if o.isUseDb {
-			buf.WriteString(fmt.Sprintf("USE `%s`;\n", dbStr))
+			buf.WriteString(fmt.Sprintf("USE %s;\n", quoteIdentifier(dbStr)))
 		}
 
+		// views are created once all tables exist, in place of their placeholder
+		var views []string
 		for _, table := range tables {
-			tt, err := getTableType(db, table)
+			ti, err := getTableInfo(db, dbStr, table)
 			if err != nil {
-				return err
+				o.logger.Error("cannot read the table type", "phase", PhaseList, "db", dbStr, "table", table, "err", err)
+				return result, o.dumpError(PhaseList, dbStr, table, err)
 			}
+			if ti.typ == "" {
+				o.tracker.skip(dbStr, table, fmt.Sprintf("unsupported table type %q", ti.rawType))
+				o.logger.Warn("object skipped", "phase", PhaseList, "db", dbStr, "table", table, "type", ti.rawType)
+				continue
+			}
+			o.tracker.startTable(dbStr, table, ti.typ, ti.rows)
 
-			if tt == "TABLE" {
-				if o.isDropTable {
-					buf.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", table))
-				}
-
+			if ti.typ == "TABLE" {
 				// Export table structure
 				err = o.writeTableStruct(db, table, buf)
 				if err != nil {
-					if o.log {
-						log.Printf("[error] %v \n", err)
-					}
-					return err
+					o.logger.Error("cannot dump the table structure", "phase", PhaseSchema, "db", dbStr, "table", table, "err", err)
+					return result, o.dumpError(PhaseSchema, dbStr, table, err)
 				}
 				// Export table data if set
 				if o.isData {
-					err = writeTableData(db, table, buf, o.perDataNumber)
+					err = o.writeTableData(db, table, buf)
 					if err != nil {
-						if o.log {
-							log.Printf("[error] %v \n", err)
-						}
-						return err
+						o.logger.Error("cannot dump the table data", "phase", PhaseData, "db", dbStr, "table", table, "err", err)
+						return result, o.dumpError(PhaseData, dbStr, table, err)
 					}
 				}
-				err := writeTableTrigger(db, table, buf)
+				err := o.writeTableTrigger(db, dbStr, table, buf)
 				if err != nil {
-					if o.log {
-						log.Printf("[error] %v \n", err)
-					}
-					return err
+					o.logger.Error("cannot dump the triggers", "phase", PhaseTrigger, "db", dbStr, "table", table, "err", err)
+					return result, o.dumpError(PhaseTrigger, dbStr, table, err)
 				}
 			}
-			if tt == "VIEW" {
-				if o.isDropTable {
-					buf.WriteString(fmt.Sprintf("DROP VIEW IF EXISTS  `%s`;\n", table))
-				}
-				// Export view structure
-				err = writeViewStruct(db, table, buf)
+			if ti.typ == "VIEW" {
+				// Export a placeholder table, so that views can use views created after them
+				err = o.writeViewPlaceholder(db, dbStr, table, buf)
 				if err != nil {
-					if o.log {
-						log.Printf("[error] %v \n", err)
-					}
-					return err
+					o.logger.Error("cannot dump the view placeholder", "phase", PhaseView, "db", dbStr, "table", table, "err", err)
+					return result, o.dumpError(PhaseView, dbStr, table, err)
 				}
+				views = append(views, table)
+			}
+			tr := o.tracker.table
+			o.tracker.endTable()
+			o.logger.Info("table dumped", "phase", PhaseData, "db", dbStr, "table", table, "rows", tr.Rows, "bytes", tr.Bytes)
+		}
+
+		for _, view := range views {
+			// Export view structure
+			err = o.writeViewStruct(db, view, buf)
+			if err != nil {
+				o.logger.Error("cannot dump the view", "phase", PhaseView, "db", dbStr, "table", view, "err", err)
+				return result, o.dumpError(PhaseView, dbStr, view, err)
 			}
 		}
 	}
 
 	// inject footer template
 	if err := tpl.Footer.Execute(buf, o); err != nil {
-		log.Printf("[footer] [error] %v \n", err)
-		return err
+		o.logger.Error("footer failed", "phase", PhaseFooter, "err", err)
+		return result, o.dumpError(PhaseFooter, "", "", err)
 	}
-	return nil
+	return result, nil
 }
 
-func getTableType(db *sql.DB, table string) (t string, err error) {
-	var tableType string
+// tableInfo is what information_schema.TABLES says about a table
+type tableInfo struct {
+	// typ is TABLE, VIEW or empty for other types
+	typ     string
+	rawType string
+	// estimated number of rows
+	rows int64
+}
+
+func getTableInfo(db dumpSession, dbName, table string) (ti tableInfo, err error) {
+	var rows sql.NullInt64
 	if err = db.QueryRow(
-		fmt.Sprintf("SELECT TABLE_TYPE FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = '%s'", table)).
-		Scan(&tableType); err != nil {
-		return "", err
+		"SELECT TABLE_TYPE, TABLE_ROWS FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", dbName, table).
+		Scan(&ti.rawType, &rows); err != nil {
+		return ti, err
 	}
+	ti.rows = rows.Int64
 
-	switch tableType {
+	switch ti.rawType {
 	case "BASE TABLE":
-		return "TABLE", nil
+		ti.typ = "TABLE"
 	case "VIEW":
-		return "VIEW", nil
-	default:
-		return "", nil
+		ti.typ = "VIEW"
 	}
+	return ti, nil
 }
 
-func getCreateTableSQL(db *sql.DB, table string, checkExists bool) (string, error) {
+func getCreateTableSQL(db dumpSession, table string, checkExists bool) (string, error) {
 	var createTableSQL string
 
-	err := db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table)).Scan(&table, &createTableSQL)
+	err := db.QueryRow("SHOW CREATE TABLE "+quoteIdentifier(table)).Scan(&table, &createTableSQL)
 	if err != nil {
 		return "", err
 	}
@@ -296,7 +418,7 @@ func getCreateTableSQL(db *sql.DB, table string, checkExists bool) (string, erro
 	return createTableSQL, nil
 }
 
-func getDBs(db *sql.DB) ([]string, error) {
+func getDBs(db dumpSession) ([]string, error) {
 	var dbs []string
 	rows, err := db.Query("SHOW DATABASES")
 	if err != nil {
@@ -315,7 +437,7 @@ func getDBs(db *sql.DB) ([]string, error) {
 	return dbs, nil
 }
 
-func getAllTables(db *sql.DB) ([]string, error) {
+func getAllTables(db dumpSession) ([]string, error) {
 	var tables []string
 	rows, err := db.Query("SHOW TABLES")
 	if err != nil {
@@ -334,10 +456,13 @@ func getAllTables(db *sql.DB) ([]string, error) {
 	return tables, nil
 }
 
-func (o dumpOption) writeTableStruct(db *sql.DB, table string, buf *bufio.Writer) error {
+func (o dumpOption) writeTableStruct(db dumpSession, table string, buf *bufio.Writer) error {
 	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("-- Table structure for %s\n", table))
+	buf.WriteString(fmt.Sprintf("-- %s%s\n", markerTableStructure, commentName(table)))
 	buf.WriteString("-- ----------------------------\n")
+	if o.isDropTable {
+		buf.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", quoteIdentifier(table)))
+	}
 
 	createTableSQL, err := getCreateTableSQL(db, table, !o.isDropTable)
 	if err != nil {
@@ -350,34 +475,93 @@ func (o dumpOption) writeTableStruct(db *sql.DB, table string, buf *bufio.Writer
 	return nil
 }
 
-func writeViewStruct(db *sql.DB, table string, buf *bufio.Writer) error {
+// writeViewPlaceholder writes a table with the columns of a view, replaced
+// by the view once all the tables and views of the database are created
+func (o dumpOption) writeViewPlaceholder(db dumpSession, dbName, table string, buf *bufio.Writer) error {
+	columns, err := getViewColumns(db, dbName, table)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- %s%s\n", markerViewPlaceholder, commentName(table)))
+	buf.WriteString("-- ----------------------------\n")
+	if len(columns) == 0 {
+		// an invalid view, e.g. using a dropped table
+		o.logger.Warn("view without columns, no placeholder", "phase", PhaseView, "db", dbName, "table", table)
+		buf.WriteString("\n")
+		return nil
+	}
+	if o.isDropTable {
+		buf.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", quoteIdentifier(table)))
+		buf.WriteString(fmt.Sprintf("DROP VIEW IF EXISTS %s;\n", quoteIdentifier(table)))
+		buf.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", quoteIdentifier(table)))
+	} else {
+		buf.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", quoteIdentifier(table)))
+	}
+	for i, column := range columns {
+		buf.WriteString(fmt.Sprintf("  %s tinyint NOT NULL", quoteIdentifier(column)))
+		if i < len(columns)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(");\n\n")
+	return nil
+}
+
+func getViewColumns(db dumpSession, dbName, view string) ([]string, error) {
+	rows, err := db.Query(
+		"SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", dbName, view)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+func (o dumpOption) writeViewStruct(db dumpSession, table string, buf *bufio.Writer) error {
 	var (
 		createTableSQL, charact, connect string
 	)
 
 	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("-- View structure for %s\n", table))
+	buf.WriteString(fmt.Sprintf("-- %s%s\n", markerView, commentName(table)))
 	buf.WriteString("-- ----------------------------\n")
+	// the placeholder
+	buf.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", quoteIdentifier(table)))
+	if o.isDropTable {
+		buf.WriteString(fmt.Sprintf("DROP VIEW IF EXISTS %s;\n", quoteIdentifier(table)))
+	}
 
-	err := db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table)).Scan(&table, &createTableSQL, &charact, &connect)
+	err := db.QueryRow("SHOW CREATE TABLE "+quoteIdentifier(table)).Scan(&table, &createTableSQL, &charact, &connect)
 	if err != nil {
 		return err
 	}
-	buf.WriteString(createTableSQL)
+	buf.WriteString(o.viewSecurity(createTableSQL))
 	buf.WriteString(";")
 
 	buf.WriteString("\n\n")
 	return nil
 }
 
-func writeTableData(db *sql.DB, table string, buf *bufio.Writer, perDataNumber int) error {
+func (o dumpOption) writeTableData(db dumpSession, table string, buf *bufio.Writer) error {
 	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("--Dumping data for table %s\n", table))
+	buf.WriteString(fmt.Sprintf("-- %s%s\n", markerTableData, commentName(table)))
 	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("LOCK TABLES `%s` WRITE;\n", table))
-	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE `%s` DISABLE KEYS */;\n", table))
+	buf.WriteString(fmt.Sprintf("LOCK TABLES %s WRITE;\n", quoteIdentifier(table)))
+	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE %s DISABLE KEYS */;\n", quoteIdentifier(table)))
 
-	lineRows, err := db.Query(fmt.Sprintf("SELECT * FROM `%s`", table))
+	lineRows, err := db.Query("SELECT * FROM " + quoteIdentifier(table))
 	if err != nil {
 		return err
 	}
@@ -396,16 +580,20 @@ func writeTableData(db *sql.DB, table string, buf *bufio.Writer, perDataNumber i
 	var values [][]interface{}
 	rowId := 0
 
-	cols := strings.Join(columns, "`,`")
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	cols := strings.Join(quoted, ",")
 
 	for lineRows.Next() {
 		ssql := ""
-		if rowId == 0 || perDataNumber < 2 || rowId%perDataNumber == 0 {
+		if rowId == 0 || o.perDataNumber < 2 || rowId%o.perDataNumber == 0 {
 			if rowId > 0 {
 				ssql = ";\n"
 			}
 
-			ssql += "INSERT INTO `" + table + "` (`" + cols + "`) VALUES \n"
+			ssql += "INSERT INTO " + quoteIdentifier(table) + " (" + cols + ") VALUES \n"
 		} else {
 			buf.WriteString(",\n")
 		}
@@ -419,23 +607,24 @@ func writeTableData(db *sql.DB, table string, buf *bufio.Writer, perDataNumber i
 		if err != nil {
 			return err
 		}
-		rowString, err := buildRowData(row, columnTypes)
+		rowString, err := buildRowData(row, columnTypes, o.enc)
 		if err != nil {
 			return err
 		}
 		ssql += "(" + rowString + ")"
 		rowId += 1
 		buf.WriteString(ssql)
+		o.tracker.rows(int64(rowId))
 		values = append(values, row)
 	}
 
 	buf.WriteString(";\n")
-	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE `%s` ENABLE KEYS */;\n", table))
+	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE %s ENABLE KEYS */;\n", quoteIdentifier(table)))
 	buf.WriteString("UNLOCK TABLES;\n\n")
 	return nil
 }
 
-func buildRowData(row []interface{}, columnTypes []*sql.ColumnType) (ssql string, err error) {
+func buildRowData(row []interface{}, columnTypes []*sql.ColumnType, enc literalEncoder) (ssql string, err error) {
 	for i, col := range row {
 		if col == nil {
 			ssql += "NULL"
@@ -444,55 +633,50 @@ func buildRowData(row []interface{}, columnTypes []*sql.ColumnType) (ssql string
 
 			Type = strings.TrimSpace(strings.Replace(Type, "UNSIGNED", "", -1))
 			switch Type {
-			case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT":
-				ssql += fmt.Sprintf("%d", col)
-
-			case "FLOAT", "DOUBLE":
-				if bs, ok := col.([]byte); ok {
-					ssql += fmt.Sprintf("%s", string(bs))
-				} else {
-					ssql += fmt.Sprintf("%f", col)
-				}
-
-			case "DECIMAL", "DEC":
-				ssql += fmt.Sprintf("%s", col)
-
-			case "DATE":
-				t, ok := col.(time.Time)
-				if !ok {
-					return "", err
+			case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "DECIMAL", "DEC":
+				v, err := encodeNumeric(col, Type, -1)
+				if err != nil {
+					return "", &DumpError{Phase: PhaseData, Column: columnTypes[i].Name(), Err: err}
 				}
-				ssql += fmt.Sprintf("'%s'", t.Format("2006-01-02"))
+				ssql += v
 
-			case "DATETIME", "TIMESTAMP":
-				t, ok := col.(time.Time)
-				if !ok {
-					return "", err
+			case "FLOAT", "DOUBLE":
+				v, err := encodeNumeric(col, Type, columnScale(columnTypes[i]))
+				if err != nil {
+					return "", &DumpError{Phase: PhaseData, Column: columnTypes[i].Name(), Err: err}
 				}
-				ssql += fmt.Sprintf("'%s'", t.Format(DEFAULT_LOG_TIMESTAMP))
+				ssql += v
 
-			case "TIME":
-				t, ok := col.([]byte)
-				if !ok {
-					return "", err
+			case "DATE", "DATETIME", "TIMESTAMP", "TIME":
+				v, err := encodeTemporal(col, Type, columnFsp(columnTypes[i]))
+				if err != nil {
+					return "", &DumpError{Phase: PhaseData, Column: columnTypes[i].Name(), Err: err}
 				}
-				ssql += fmt.Sprintf("'%s'", string(t))
+				ssql += v
 
 			case "YEAR":
-				t, ok := col.([]byte)
-				if !ok {
-					return "", err
+				switch v := col.(type) {
+				case int64:
+					ssql += fmt.Sprintf("%d", v)
+				case []byte:
+					ssql += string(v)
+				default:
+					return "", &DumpError{Phase: PhaseData, Column: columnTypes[i].Name(), Err: fmt.Errorf("cannot encode %T as %s", col, Type)}
 				}
-				ssql += fmt.Sprintf("%s", string(t))
 
-			case "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT":
-				ssql += fmt.Sprintf("'%s'", sanitize(fmt.Sprintf("%s", col)))
+			case "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM", "SET", "JSON":
+				v, err := enc.encodeString(col, Type)
+				if err != nil {
+					return "", &DumpError{Phase: PhaseData, Column: columnTypes[i].Name(), Err: err}
+				}
+				ssql += v
 
 			case "BIT", "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB":
-				ssql += fmt.Sprintf("0x%X", col)
-
-			case "ENUM", "SET", "JSON":
-				ssql += fmt.Sprintf("'%s'", col)
+				v, err := encodeBinary(col, Type)
+				if err != nil {
+					return "", &DumpError{Phase: PhaseData, Column: columnTypes[i].Name(), Err: err}
+				}
+				ssql += v
 
 			case "BOOL", "BOOLEAN":
 				if col.(bool) {
@@ -502,7 +686,7 @@ func buildRowData(row []interface{}, columnTypes []*sql.ColumnType) (ssql string
 				}
 
 			default:
-				return "", fmt.Errorf("unsupported type: %s", Type)
+				return "", &DumpError{Phase: PhaseData, Column: columnTypes[i].Name(), Err: fmt.Errorf("%w %s", ErrUnsupportedType, Type)}
 			}
 		}
 		if i < len(row)-1 {
@@ -512,73 +696,144 @@ func buildRowData(row []interface{}, columnTypes []*sql.ColumnType) (ssql string
 	return ssql, nil
 }
 
-func writeTableTrigger(db *sql.DB, table string, buf *bufio.Writer) error {
-	var sql []string
-
-	triggers, err := getTrigger(db, table)
-	if err != nil {
+func (o *dumpOption) writeTableTrigger(db dumpSession, dbName, table string, buf *bufio.Writer) error {
+	names, err := o.getTriggers(db, dbName, table)
+	if err != nil || len(names) == 0 {
 		return err
 	}
-	if len(triggers) > 0 {
-		sql = append(sql, "-- ----------------------------")
-		sql = append(sql, fmt.Sprintf("-- Dump table triggers of %s--------", table))
-		sql = append(sql, "-- ----------------------------")
-	}
-	for _, v := range triggers {
-		sql = append(sql, "DELIMITER ;;")
-		sql = append(sql, "/*!50003 SET SESSION SQL_MODE=\"\" */;;")
-		sql = append(sql, fmt.Sprintf("/*!50003 CREATE TRIGGER `%s` %s %s ON `%s` FOR EACH ROW %s */;;", v.Trigger, v.Timing, v.Event, v.Table, v.Statement))
-		sql = append(sql, "DELIMITER ;")
-		sql = append(sql, "/*!50003 SET SESSION SQL_MODE=@OLD_SQL_MODE */;\n")
+
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- %s%s%s\n", markerTriggers, commentName(table), markerTriggersEnd))
+	buf.WriteString("-- ----------------------------\n")
+	for _, name := range names {
+		t, err := getCreateTrigger(db, name)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(o.formatTrigger(t))
 	}
-	buf.WriteString(strings.Join(sql, "\n"))
+	buf.WriteString("\n")
 	return nil
 }
 
-func getTrigger(db *sql.DB, table string) (trigger []triggerStruct, err error) {
-	if allTriggers != nil {
-		trigger = allTriggers[table]
-		return trigger, nil
-	} else {
-		allTriggers = make(map[string][]triggerStruct)
+// createTrigger is what SHOW CREATE TRIGGER says about a trigger
+type createTrigger struct {
+	name      string
+	statement string
+	// settings the trigger was created with
+	sqlMode             string
+	characterSetClient  string
+	collationConnection string
+}
+
+// formatTrigger writes the statements creating t with its sql_mode and character set,
+// then restoring the ones of the dump
+func (o dumpOption) formatTrigger(t createTrigger) string {
+	var sb strings.Builder
+	if o.isDropTable {
+		sb.WriteString(fmt.Sprintf("DROP TRIGGER IF EXISTS %s;\n", quoteIdentifier(t.name)))
+	}
+	if t.characterSetClient != "" {
+		sb.WriteString("/*!50003 SET @saved_cs_client = @@character_set_client */;\n")
+		sb.WriteString("/*!50003 SET @saved_cs_results = @@character_set_results */;\n")
+		sb.WriteString("/*!50003 SET @saved_col_connection = @@collation_connection */;\n")
+		sb.WriteString(fmt.Sprintf("/*!50003 SET character_set_client = %s */;\n", t.characterSetClient))
+		sb.WriteString(fmt.Sprintf("/*!50003 SET character_set_results = %s */;\n", t.characterSetClient))
+		if t.collationConnection != "" {
+			sb.WriteString(fmt.Sprintf("/*!50003 SET collation_connection = %s */;\n", t.collationConnection))
+		}
 	}
+	sb.WriteString(fmt.Sprintf("/*!50003 SET SESSION SQL_MODE='%s' */;\n", t.sqlMode))
+	sb.WriteString("DELIMITER ;;\n")
+	sb.WriteString(t.statement)
+	sb.WriteString(" ;;\n")
+	sb.WriteString("DELIMITER ;\n")
+	// a literal, so that Source knows whether backslash escapes are in effect
+	sb.WriteString(fmt.Sprintf("/*!50003 SET SESSION SQL_MODE='%s' */;\n", o.SQLMode))
+	if t.characterSetClient != "" {
+		sb.WriteString("/*!50003 SET character_set_client = @saved_cs_client */;\n")
+		sb.WriteString("/*!50003 SET character_set_results = @saved_cs_results */;\n")
+		sb.WriteString("/*!50003 SET collation_connection = @saved_col_connection */;\n")
+	}
+	return sb.String()
+}
 
-	trgs, err := db.Query("SHOW TRIGGERS")
-	if err != nil {
-		return trigger, err
+// getTriggers returns the triggers of a table, in the order they fire.
+// The triggers of a database are read once.
+func (o *dumpOption) getTriggers(db dumpSession, dbName, table string) ([]string, error) {
+	if byTable, ok := o.triggers[dbName]; ok {
+		return byTable[table], nil
 	}
-	defer trgs.Close()
 
-	var columns []string
-	columns, err = trgs.Columns()
+	// creating the triggers in their action order preserves FOLLOWS and PRECEDES
+	rows, err := db.Query(
+		"SELECT EVENT_OBJECT_TABLE, TRIGGER_NAME FROM INFORMATION_SCHEMA.TRIGGERS "+
+			"WHERE TRIGGER_SCHEMA = ? ORDER BY EVENT_OBJECT_TABLE, ACTION_ORDER, TRIGGER_NAME", dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	for trgs.Next() {
-		trgrow := make([]interface{}, len(columns))
-		rowPointers := make([]interface{}, len(columns))
-		for i := range columns {
-			rowPointers[i] = &trgrow[i]
-		}
-		err = trgs.Scan(rowPointers...)
-		if err != nil {
-			return trigger, err
+	byTable := make(map[string][]string)
+	for rows.Next() {
+		var tbl, name string
+		if err = rows.Scan(&tbl, &name); err != nil {
+			return nil, err
 		}
+		byTable[tbl] = append(byTable[tbl], name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-		var trigger triggerStruct
-		for k, v := range trgrow {
-			switch columns[k] {
-			case "Table":
-				trigger.Table = fmt.Sprintf("%s", v)
-			case "Event":
-				trigger.Event = fmt.Sprintf("%s", v)
-			case "Trigger":
-				trigger.Trigger = fmt.Sprintf("%s", v)
-			case "Statement":
-				trigger.Statement = fmt.Sprintf("%s", v)
-			case "Timing":
-				trigger.Timing = fmt.Sprintf("%s", v)
-			}
+	if o.triggers == nil {
+		o.triggers = make(map[string]map[string][]string)
+	}
+	o.triggers[dbName] = byTable
+	return byTable[table], nil
+}
+
+func getCreateTrigger(db dumpSession, name string) (t createTrigger, err error) {
+	rows, err := db.Query("SHOW CREATE TRIGGER " + quoteIdentifier(name))
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return t, err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = fmt.Errorf("trigger %s not found", quoteIdentifier(name))
 		}
-		allTriggers[trigger.Table] = append(allTriggers[trigger.Table], trigger)
+		return t, err
+	}
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err = rows.Scan(pointers...); err != nil {
+		return t, err
+	}
+
+	t.name = name
+	for i, column := range columns {
+		switch column {
+		case "sql_mode":
+			t.sqlMode = values[i].String
+		case "SQL Original Statement":
+			t.statement = values[i].String
+		case "character_set_client":
+			t.characterSetClient = values[i].String
+		case "collation_connection":
+			t.collationConnection = values[i].String
+		}
+	}
+	if t.statement == "" {
+		return t, fmt.Errorf("no definition for trigger %s", quoteIdentifier(name))
 	}
-	return allTriggers[table], nil
+	return t, rows.Err()
 }
@@ -0,0 +1,229 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+)
+
+// statements of a data section buffered for a worker
+const loadJobBuffer = 64
+
+type (
+	// parallelLoader loads the data sections of a dump concurrently,
+	// each on its own connection, while the main session runs the DDL in order.
+	// View and trigger sections, as well as routines, are deferred until all data is loaded.
+	parallelLoader struct {
+		ctx    context.Context
+		db     *sql.DB
+		o      *sourceOption
+		dbName string
+
+		jobs    chan *loadJob
+		current *loadJob
+		wg      sync.WaitGroup
+		once    sync.Once
+
+		mu      sync.Mutex
+		err     error
+		reports []*RestoreReport
+
+		// shadow copies of WithShadowRestore, nil if disabled
+		shadow *shadowRestore
+		// progress of WithProgress, nil if disabled
+		progress *progressTracker
+	}
+
+	// loadJob is the data section of one table
+	loadJob struct {
+		table string
+		// session statements of the main session when the section started
+		session []string
+		stmts   chan Statement
+	}
+)
+
+func newParallelLoader(ctx context.Context, db *sql.DB, o *sourceOption, dbName string) *parallelLoader {
+	p := &parallelLoader{
+		ctx:    ctx,
+		db:     db,
+		o:      o,
+		dbName: dbName,
+		jobs:   make(chan *loadJob),
+	}
+	for i := 0; i < o.parallelism; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+// worker loads the data sections it receives on its own session
+func (p *parallelLoader) worker() {
+	defer p.wg.Done()
+
+	var r *restorer
+	defer func() {
+		if r != nil {
+			r.db.close()
+		}
+	}()
+
+	for job := range p.jobs {
+		if p.failed() != nil {
+			drain(job)
+			continue
+		}
+		if r == nil {
+			w, err := openSession(p.ctx, p.db, p.o, p.dbName)
+			if err != nil {
+				p.fail(err)
+				drain(job)
+				continue
+			}
+			r = newRestorer(p.o, w, nil, p.dbName)
+			r.shadow = p.shadow
+			r.progress = p.progress
+			r.open = func() (*dbWrapper, error) {
+				return openSession(p.ctx, p.db, p.o, p.dbName)
+			}
+			p.mu.Lock()
+			p.reports = append(p.reports, r.report)
+			p.mu.Unlock()
+		}
+
+		if err := p.load(r, job); err != nil {
+			p.fail(err)
+			drain(job)
+		}
+	}
+}
+
+// load replays the main session state, then runs and commits the statements of job
+func (p *parallelLoader) load(r *restorer, job *loadJob) error {
+	for _, ssql := range job.session {
+		if err := r.exec(Statement{}, ssql); err != nil {
+			return err
+		}
+	}
+	for stmt := range job.stmts {
+		if p.failed() != nil {
+			drain(job)
+			return nil
+		}
+		if err := r.statement(stmt); err != nil {
+			return err
+		}
+		if r.commitDue() {
+			if err := r.commit(); err != nil {
+				return err
+			}
+		}
+	}
+	return r.commit()
+}
+
+func drain(job *loadJob) {
+	for range job.stmts {
+	}
+}
+
+func (p *parallelLoader) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *parallelLoader) failed() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// route sends stmt to a worker when it belongs to a data section, or defers it.
+// It returns false when stmt has to run on the main session.
+func (p *parallelLoader) route(r *restorer, stmt Statement, info statementInfo) (bool, error) {
+	if err := p.failed(); err != nil {
+		return false, err
+	}
+
+	switch {
+	case r.section.kind == sectionData && (info.table == "" || info.table == r.section.table):
+		if p.current == nil {
+			// statements on the main session must not be reordered with the section
+			if err := r.flush(); err != nil {
+				return false, err
+			}
+			p.current = &loadJob{
+				table:   r.section.table,
+				session: append([]string(nil), r.session...),
+				stmts:   make(chan Statement, loadJobBuffer),
+			}
+			p.jobs <- p.current
+		}
+		p.current.stmts <- stmt
+		if info.verb == "UNLOCK" {
+			p.end()
+		}
+		return true, nil
+
+	case r.section.kind == sectionView, r.section.kind == sectionTrigger,
+		info.object == "PROCEDURE", info.object == "FUNCTION", info.object == "EVENT":
+		p.end()
+		r.deferred = append(r.deferred, deferredStatement{database: r.database, stmt: stmt})
+		return true, nil
+	}
+
+	p.end()
+	if r.section.kind == sectionData {
+		// a statement on another table ends the section
+		r.section = section{}
+	}
+	return false, nil
+}
+
+// end closes the data section being sent to a worker
+func (p *parallelLoader) end() {
+	if p.current != nil {
+		close(p.current.stmts)
+		p.current = nil
+	}
+}
+
+// close stops the workers once they loaded all sections
+func (p *parallelLoader) close() {
+	p.once.Do(func() {
+		p.end()
+		close(p.jobs)
+		p.wg.Wait()
+	})
+}
+
+// finish waits for the data to be loaded and merges the reports of the workers
+func (p *parallelLoader) finish(r *restorer) error {
+	p.close()
+
+	for _, report := range p.reports {
+		r.report.Statements += report.Statements
+		r.report.Failures = append(r.report.Failures, report.Failures...)
+		if r.report.Plan != nil {
+			r.report.Plan.Statements = append(r.report.Plan.Statements, report.Plan.Statements...)
+		}
+	}
+	sort.SliceStable(r.report.Failures, func(i, j int) bool {
+		return r.report.Failures[i].Offset < r.report.Failures[j].Offset
+	})
+	if r.report.Plan != nil {
+		sort.SliceStable(r.report.Plan.Statements, func(i, j int) bool {
+			return r.report.Plan.Statements[i].Offset < r.report.Plan.Statements[j].Offset
+		})
+	}
+	if p.err != nil {
+		r.o.logger.Error("parallel load failed", "phase", PhaseExec, "err", p.err)
+		return p.err
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package mysqldump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// a table with its data, triggers and a view, as written by Dump
+const tableDump = "USE `db`;\n" +
+	"-- ----------------------------\n-- Table structure for t\n-- ----------------------------\n" +
+	"DROP TABLE IF EXISTS `t`;\n" +
+	"CREATE TABLE `t` (`a` int);\n" +
+	"-- ----------------------------\n-- Dumping data for table t\n-- ----------------------------\n" +
+	"LOCK TABLES `t` WRITE;\n" +
+	"INSERT INTO `t` VALUES (1);\n" +
+	"UNLOCK TABLES;\n" +
+	"-- ----------------------------\n-- Dump table triggers of t--------\n-- ----------------------------\n" +
+	"CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1;\n" +
+	"-- ----------------------------\n-- View structure for v\n-- ----------------------------\n" +
+	"CREATE VIEW `v` AS select `a` from `t`;\n" +
+	"CREATE PROCEDURE `p`() SELECT 1;\n"
+
+// two databases, the views and triggers of the first one before the USE of the second one
+const multiDatabaseDump = "USE `db1`;\n" +
+	"-- ----------------------------\n-- Table structure for t\n-- ----------------------------\n" +
+	"CREATE TABLE `t` (`a` int);\n" +
+	"-- ----------------------------\n-- Dump table triggers of t--------\n-- ----------------------------\n" +
+	"/*!50003 SET SESSION SQL_MODE='' */;\n" +
+	"DELIMITER ;;\nCREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1 ;;\nDELIMITER ;\n" +
+	"-- ----------------------------\n-- Temporary view structure for v\n-- ----------------------------\n" +
+	"CREATE TABLE IF NOT EXISTS `v` (\n  `a` tinyint NOT NULL\n);\n" +
+	"-- ----------------------------\n-- Final view structure for v\n-- ----------------------------\n" +
+	"DROP TABLE IF EXISTS `v`;\n" +
+	"CREATE VIEW `v` AS select `a` from `t`;\n" +
+	"USE `db2`;\n" +
+	"-- ----------------------------\n-- Table structure for t2\n-- ----------------------------\n" +
+	"CREATE TABLE `t2` (`a` int);\n"
+
+// handleAll passes the statements of script to r.handle
+func handleAll(t *testing.T, r *restorer, script string) {
+	t.Helper()
+	r.sc = NewStatementScanner(strings.NewReader(script))
+	for r.sc.Scan() {
+		if err := r.handle(r.sc.Statement()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// executed lists the statements run so far, prefixed with the database they ran in
+func executed(r *restorer) []string {
+	var stmts []string
+	for _, s := range r.report.Plan.Statements {
+		stmts = append(stmts, s.Database+": "+s.Statement)
+	}
+	return stmts
+}
+
+// deferredStatements lists the deferred statements, prefixed with their database
+func deferredStatements(r *restorer) []string {
+	var stmts []string
+	for _, d := range r.deferred {
+		stmts = append(stmts, d.database+": "+d.stmt.Text)
+	}
+	return stmts
+}
+
+func Test_parallelLoader_route(t *testing.T) {
+	r := newDryRunRestorer("db")
+	r.par = &parallelLoader{o: r.o, jobs: make(chan *loadJob, 1)}
+	handleAll(t, r, tableDump)
+
+	wantExecuted := []string{"db: USE `db`", "db: DROP TABLE IF EXISTS `t`", "db: CREATE TABLE `t` (`a` int)"}
+	if got := executed(r); !reflect.DeepEqual(got, wantExecuted) {
+		t.Errorf("executed = %q\nwant %q", got, wantExecuted)
+	}
+
+	// the data section is sent to a worker as a whole
+	job := <-r.par.jobs
+	var loaded []string
+	for stmt := range job.stmts {
+		loaded = append(loaded, stmt.Text)
+	}
+	wantLoaded := []string{"LOCK TABLES `t` WRITE", "INSERT INTO `t` VALUES (1)", "UNLOCK TABLES"}
+	if job.table != "t" || !reflect.DeepEqual(loaded, wantLoaded) {
+		t.Errorf("job %s = %q\nwant t = %q", job.table, loaded, wantLoaded)
+	}
+
+	wantDeferred := []string{
+		"db: CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1",
+		"db: CREATE VIEW `v` AS select `a` from `t`",
+		"db: CREATE PROCEDURE `p`() SELECT 1",
+	}
+	if got := deferredStatements(r); !reflect.DeepEqual(got, wantDeferred) {
+		t.Errorf("deferred = %q\nwant %q", got, wantDeferred)
+	}
+}
+
+func Test_parallelLoader_route_databases(t *testing.T) {
+	r := newDryRunRestorer("")
+	r.par = &parallelLoader{o: r.o, jobs: make(chan *loadJob)}
+	handleAll(t, r, multiDatabaseDump)
+
+	wantExecuted := []string{
+		"db1: USE `db1`",
+		"db1: CREATE TABLE `t` (`a` int)",
+		"db2: USE `db2`",
+		"db2: CREATE TABLE `t2` (`a` int)",
+	}
+	if got := executed(r); !reflect.DeepEqual(got, wantExecuted) {
+		t.Errorf("executed = %q\nwant %q", got, wantExecuted)
+	}
+	wantDeferred := []string{
+		"db1: /*!50003 SET SESSION SQL_MODE='' */",
+		"db1: CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1",
+		"db1: CREATE TABLE IF NOT EXISTS `v` (\n  `a` tinyint NOT NULL\n)",
+		"db1: DROP TABLE IF EXISTS `v`",
+		"db1: CREATE VIEW `v` AS select `a` from `t`",
+	}
+	if got := deferredStatements(r); !reflect.DeepEqual(got, wantDeferred) {
+		t.Errorf("deferred = %q\nwant %q", got, wantDeferred)
+	}
+}
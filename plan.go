@@ -0,0 +1,139 @@
+package mysqldump
+
+// StatementKind classifies the statements of a RestorePlan
+type StatementKind int
+
+const (
+	// KindUnknown is a statement the parser could not classify
+	KindUnknown StatementKind = iota
+	// KindDDL creates, alters or drops objects
+	KindDDL
+	// KindDML changes rows
+	KindDML
+	// KindSession changes the session state: SET, USE, LOCK TABLES...
+	KindSession
+	// KindTransaction starts or ends a transaction
+	KindTransaction
+)
+
+func (k StatementKind) String() string {
+	switch k {
+	case KindDDL:
+		return "DDL"
+	case KindDML:
+		return "DML"
+	case KindSession:
+		return "session"
+	case KindTransaction:
+		return "transaction"
+	}
+	return "unknown"
+}
+
+type (
+	// RestorePlan lists what Source would execute, returned in RestoreReport.Plan with WithDryRun
+	RestorePlan struct {
+		Statements []PlannedStatement
+	}
+
+	// PlannedStatement is a statement Source would execute
+	PlannedStatement struct {
+		// Offset and Line of the statement in the input
+		Offset int64
+		Line   int
+		Kind   StatementKind
+		// Verb is the first keyword, e.g. INSERT
+		Verb string
+		// Database and Table targeted, empty if unknown
+		Database string
+		Table    string
+		// Rows is the number of rows inserted, estimated from the VALUES tuples
+		Rows int
+		// Statement text, truncated to 1024 bytes
+		Statement string
+	}
+)
+
+// Unclassified returns the statements the parser could not classify
+func (p *RestorePlan) Unclassified() []PlannedStatement {
+	var stmts []PlannedStatement
+	for _, s := range p.Statements {
+		if s.Kind == KindUnknown {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// Rows returns the estimated number of rows inserted into database.table
+func (p *RestorePlan) Rows(database, table string) int {
+	var rows int
+	for _, s := range p.Statements {
+		if s.Database == database && s.Table == table {
+			rows += s.Rows
+		}
+	}
+	return rows
+}
+
+// plan records query, read at the position of stmt, in the dry-run plan
+func (r *restorer) plan(stmt Statement, query string, info statementInfo) {
+	// session statements replayed internally have no position
+	if r.report.Plan == nil || stmt.Line == 0 {
+		return
+	}
+	ps := PlannedStatement{
+		Offset:    stmt.Offset,
+		Line:      stmt.Line,
+		Kind:      statementKind(info.verb),
+		Verb:      info.verb,
+		Database:  info.database,
+		Table:     info.table,
+		Statement: abbreviate(query, maxFailureStatement),
+	}
+	if ps.Database == "" && ps.Table != "" {
+		ps.Database = r.database
+	}
+	if ps.Kind == KindDML && (info.verb == "INSERT" || info.verb == "REPLACE") {
+		ps.Rows = countRows(query)
+	}
+	r.report.Plan.Statements = append(r.report.Plan.Statements, ps)
+}
+
+// statementKind classifies a statement by its verb
+func statementKind(verb string) StatementKind {
+	switch verb {
+	case "CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE":
+		return KindDDL
+	case "INSERT", "REPLACE", "UPDATE", "DELETE", "LOAD":
+		return KindDML
+	case "SET", "USE", "LOCK", "UNLOCK":
+		return KindSession
+	case "START", "BEGIN", "COMMIT", "ROLLBACK", "SAVEPOINT", "RELEASE":
+		return KindTransaction
+	}
+	return KindUnknown
+}
+
+// countRows returns the number of tuples after VALUES in an INSERT statement,
+// 0 for INSERT ... SELECT
+func countRows(text string) int {
+	var (
+		rows, depth int
+		values      bool
+	)
+	for _, tok := range tokenize(text, -1) {
+		switch {
+		case !values:
+			values = tok.is("VALUES") || tok.is("VALUE")
+		case tok.text == "(":
+			if depth == 0 {
+				rows++
+			}
+			depth++
+		case tok.text == ")":
+			depth--
+		}
+	}
+	return rows
+}
@@ -0,0 +1,43 @@
+package mysqldump
+
+import (
+	"testing"
+)
+
+func Test_countRows(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "INSERT INTO `t` VALUES (1,'a')", want: 1},
+		{text: "INSERT INTO `t` VALUES (1,'a'),(2,'(b)'),(3,CONCAT('c',')'))", want: 3},
+		{text: "INSERT INTO `t` (`value`,`b`) VALUES (1,2),(3,4)", want: 2},
+		{text: "REPLACE INTO `t` VALUE (1)", want: 1},
+		{text: "INSERT INTO `t` SELECT * FROM `u`", want: 0},
+	}
+	for _, tt := range tests {
+		if got := countRows(tt.text); got != tt.want {
+			t.Errorf("countRows(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func Test_statementKind(t *testing.T) {
+	tests := []struct {
+		text string
+		want StatementKind
+	}{
+		{text: "CREATE TABLE `t` (`a` int)", want: KindDDL},
+		{text: "/*!40000 ALTER TABLE `t` DISABLE KEYS */", want: KindDDL},
+		{text: "INSERT INTO `t` VALUES (1)", want: KindDML},
+		{text: "/*!40101 SET NAMES utf8mb4 */", want: KindSession},
+		{text: "LOCK TABLES `t` WRITE", want: KindSession},
+		{text: "COMMIT", want: KindTransaction},
+		{text: "GRANT ALL ON *.* TO 'u'", want: KindUnknown},
+	}
+	for _, tt := range tests {
+		if got := statementKind(parseStatement(tt.text).verb); got != tt.want {
+			t.Errorf("statementKind(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package mysqldump
 import (
 	"compress/flate"
 	"io"
+	"log/slog"
+	"strings"
 )
 
 /*
@@ -60,6 +62,33 @@ func WithAllTables() DumpOption {
 	}
 }
 
+// WithDependencyOrder Export tables after the tables they reference with foreign keys,
+// and views after the tables and views they use, so the dump can be restored without
+// FOREIGN_KEY_CHECKS=0 or table by table. Cycles are reported in DumpResult.Cycles.
+func WithDependencyOrder() DumpOption {
+	return func(option *dumpOption) {
+		option.dependencyOrder = true
+	}
+}
+
+// WithViewDefiner Replace the DEFINER of views by definer, e.g. "`app`@`%`" or "CURRENT_USER".
+// An empty definer removes the clause, so the views belong to the user restoring the dump.
+// (Default: the DEFINER of the server)
+func WithViewDefiner(definer string) DumpOption {
+	return func(option *dumpOption) {
+		option.isViewDefiner = true
+		option.viewDefiner = definer
+	}
+}
+
+// WithViewSQLSecurity Replace the SQL SECURITY of views by DEFINER or INVOKER
+// (Default: the SQL SECURITY of the server)
+func WithViewSQLSecurity(security string) DumpOption {
+	return func(option *dumpOption) {
+		option.viewSQLSecurity = strings.ToUpper(security)
+	}
+}
+
 // WithMultiInsert Export multi-inserts in one command
 func WithMultiInsert(num int) DumpOption {
 	return func(option *dumpOption) {
@@ -74,13 +103,37 @@ func WithWriter(writer io.Writer) DumpOption {
 	}
 }
 
-// WithLogErrors Whether to output logs
+// WithLogErrors Log with slog.Default, unless a logger is given with WithLogger
 func WithLogErrors() DumpOption {
 	return func(option *dumpOption) {
 		option.log = true
 	}
 }
 
+// WithLogger Log the dump with l, with the db, table, phase and rows as attributes.
+// Nothing is logged by default.
+func WithLogger(l *slog.Logger) DumpOption {
+	return func(option *dumpOption) {
+		option.logger = l
+	}
+}
+
+// WithNoBackslashEscapes Escape string literals for the NO_BACKSLASH_ESCAPES sql_mode,
+// the dump header enables it so the output is restored the same way
+func WithNoBackslashEscapes() DumpOption {
+	return func(option *dumpOption) {
+		option.noBackslashEscapes = true
+	}
+}
+
+// WithDumpProgress Call fn when a database or table starts, when a table ends,
+// and about every second while rows are written
+func WithDumpProgress(fn func(DumpEvent)) DumpOption {
+	return func(option *dumpOption) {
+		option.progress = fn
+	}
+}
+
 // WithCompression Whether to compress desired file with gzip
 func WithCompression(level string) DumpOption {
 	return func(option *dumpOption) {
@@ -0,0 +1,116 @@
+package mysqldump
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// minimum interval between two WithProgress calls
+const progressInterval = time.Second
+
+// SourceProgress is reported by WithProgress while Source runs
+type SourceProgress struct {
+	// BytesRead of the input, including the part skipped when resuming
+	BytesRead int64
+	// TotalBytes of the input, 0 if unknown
+	TotalBytes int64
+	// Statements executed and Rows inserted so far
+	Statements int64
+	Rows       int64
+	// Database and Table of the statement being restored
+	Database string
+	Table    string
+	Elapsed  time.Duration
+	// BytesPerSecond read since the start
+	BytesPerSecond float64
+	// Percent of the input read and estimated time remaining, 0 if the size is unknown
+	Percent float64
+	ETA     time.Duration
+	// Done is set on the last report
+	Done bool
+}
+
+// progressTracker counts what Source executed, shared by the sessions of a restore
+type progressTracker struct {
+	fn    func(SourceProgress)
+	total int64
+
+	start       time.Time
+	startOffset int64
+	last        time.Time
+
+	statements atomic.Int64
+	rows       atomic.Int64
+}
+
+func newProgressTracker(fn func(SourceProgress), reader io.Reader) *progressTracker {
+	return &progressTracker{
+		fn:    fn,
+		total: inputSize(reader),
+		start: time.Now(),
+	}
+}
+
+// inputSize returns the size of an *os.File or of a reader implementing Size(), 0 otherwise
+func inputSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case *os.File:
+		if fi, err := r.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size()
+		}
+	case interface{ Size() int64 }:
+		return r.Size()
+	}
+	return 0
+}
+
+// executed counts a successful statement
+func (p *progressTracker) executed(query string, info statementInfo) {
+	p.statements.Add(1)
+	if info.verb == "INSERT" || info.verb == "REPLACE" {
+		p.rows.Add(int64(countRows(query)))
+	}
+}
+
+// report calls the callback if progressInterval elapsed since the last call, or when done
+func (p *progressTracker) report(r *restorer, done bool) {
+	now := time.Now()
+	if !done && now.Sub(p.last) < progressInterval {
+		return
+	}
+	p.last = now
+
+	sp := SourceProgress{
+		BytesRead:  r.sc.Offset(),
+		TotalBytes: p.total,
+		Statements: p.statements.Load(),
+		Rows:       p.rows.Load(),
+		Database:   r.database,
+		Table:      r.table,
+		Elapsed:    now.Sub(p.start),
+		Done:       done,
+	}
+	read := sp.BytesRead - p.startOffset
+	if secs := sp.Elapsed.Seconds(); secs > 0 {
+		sp.BytesPerSecond = float64(read) / secs
+	}
+	if p.total > 0 {
+		sp.Percent = min(100, float64(sp.BytesRead)*100/float64(p.total))
+		if read > 0 && p.total > sp.BytesRead {
+			sp.ETA = time.Duration(float64(sp.Elapsed) * float64(p.total-sp.BytesRead) / float64(read))
+		}
+	}
+	p.fn(sp)
+}
+
+// sizedReader gives the size of the input to WithProgress when it cannot be known from the reader
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+func (r sizedReader) Size() int64 {
+	return r.size
+}
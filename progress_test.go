@@ -0,0 +1,40 @@
+package mysqldump
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_inputSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	if err := os.WriteFile(path, []byte("SELECT 1;\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		name   string
+		reader io.Reader
+		want   int64
+	}{
+		{name: "file", reader: f, want: 10},
+		{name: "strings.Reader", reader: strings.NewReader("SELECT 1;"), want: 9},
+		{name: "sizedReader", reader: sizedReader{Reader: io.MultiReader(), size: 42}, want: 42},
+		{name: "unknown", reader: io.MultiReader(bytes.NewBufferString("SELECT 1;")), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inputSize(tt.reader); got != tt.want {
+				t.Errorf("inputSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// Querier runs queries on a MySQL server. It is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Conn)(nil)
+	_ Querier = (*sql.Tx)(nil)
+)
+
+// discardConn closes a connection pinned from a pool without giving it back,
+// so the session state left by a Dump or Source cannot leak to the next query of the pool
+func discardConn(conn *sql.Conn) error {
+	err := conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil
+	}
+	return err
+}
+
+// dumpSession runs the queries of a Dump on one connection, with its context
+type dumpSession struct {
+	ctx context.Context
+	q   Querier
+}
+
+func (s dumpSession) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.q.ExecContext(s.ctx, query, args...)
+}
+
+func (s dumpSession) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.q.QueryContext(s.ctx, query, args...)
+}
+
+func (s dumpSession) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.q.QueryRowContext(s.ctx, query, args...)
+}
@@ -0,0 +1,50 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// stubDriver opens connections that cannot run anything
+type stubDriver struct{}
+
+type stubConn struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func init() {
+	sql.Register("mysqldump-stub", stubDriver{})
+}
+
+func Test_discardConn(t *testing.T) {
+	pool, err := sql.Open("mysqldump-stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = discardConn(conn); err != nil {
+		t.Fatalf("discardConn() error = %v", err)
+	}
+	if n := pool.Stats().OpenConnections; n != 0 {
+		t.Errorf("open connections = %d, want the connection closed", n)
+	}
+
+	// a connection closed the usual way goes back to the pool
+	conn, _ = pool.Conn(context.Background())
+	conn.Close()
+	if n := pool.Stats().Idle; n != 1 {
+		t.Errorf("idle connections = %d, want 1", n)
+	}
+}
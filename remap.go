@@ -0,0 +1,138 @@
+package mysqldump
+
+import (
+	"sort"
+	"strings"
+)
+
+// remapDatabases rewrites the database names of a statement following m:
+// the database of USE and CREATE/DROP/ALTER DATABASE, and the database of qualified
+// names (`db`.`table` where a table is expected, `db`.`table`.`column`).
+func remapDatabases(text string, m map[string]string) string {
+	if len(m) == 0 || !containsAny(text, m) {
+		return text
+	}
+
+	tokens := tokenize(text, -1)
+	var (
+		sb   strings.Builder
+		last int
+	)
+	replace := func(tok sqlToken) {
+		target, ok := m[tok.text]
+		if !ok {
+			return
+		}
+		sb.WriteString(text[last:tok.start])
+		sb.WriteString(quoteIdentifier(target))
+		last = tok.end
+	}
+
+	info := parseStatement(text)
+	for i, tok := range tokens {
+		switch {
+		case tok.str:
+		case info.databaseTok != nil && tok.start == info.databaseTok.start:
+			replace(tok)
+		case isQualifier(tokens, i, info.object == "TRIGGER"):
+			replace(tok)
+		}
+	}
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// isQualifier reports whether tokens[i] qualifies a name with its database:
+// the first part of database.table.column, or database.table where a table is expected.
+// A two-part name elsewhere is table.column.
+func isQualifier(tokens []sqlToken, i int, trigger bool) bool {
+	tok := tokens[i]
+	if i+2 >= len(tokens) || tokens[i+1].text != "." || !tok.ident && !isIdentifierWord(tok.text) {
+		return false
+	}
+	if i > 0 && tokens[i-1].text == "." {
+		return false
+	}
+	if i+3 < len(tokens) && tokens[i+3].text == "." {
+		return true
+	}
+	if i == 0 {
+		return false
+	}
+	switch prev := tokens[i-1]; {
+	case prev.is("FROM"), prev.is("JOIN"), prev.is("INTO"), prev.is("TABLE"), prev.is("TABLES"),
+		prev.is("UPDATE"), prev.is("REFERENCES"), prev.is("VIEW"), prev.is("TO"), prev.is("EXISTS"):
+		return true
+	case prev.is("ON"):
+		// ON database.table of a trigger, ON table.column = ... of a join
+		return trigger
+	case prev.text == "(":
+		// FROM (database.table JOIN ...) of a view
+		return tableListParen(tokens, i-1)
+	case prev.text == ",":
+		return inTableList(tokens, i-1)
+	}
+	return false
+}
+
+// inTableList reports whether the comma tokens[i] separates the tables of
+// DROP TABLE, LOCK TABLES, RENAME TABLE, UPDATE or FROM,
+// rather than the columns of a select list or the values of a function call
+func inTableList(tokens []sqlToken, i int) bool {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch tok := tokens[j]; {
+		case tok.text == ")":
+			depth++
+		case tok.text == "(":
+			if depth == 0 {
+				return tableListParen(tokens, j)
+			}
+			depth--
+		case depth > 0:
+		case tok.is("TABLE"), tok.is("TABLES"), tok.is("UPDATE"), tok.is("FROM"), tok.is("JOIN"):
+			return true
+		case tok.is("SELECT"), tok.is("WHERE"), tok.is("SET"), tok.is("ON"), tok.is("BY"),
+			tok.is("HAVING"), tok.is("VALUES"), tok.is("USING"):
+			return false
+		}
+	}
+	return false
+}
+
+// tableListParen reports whether the parenthesis tokens[i] opens a list of tables
+func tableListParen(tokens []sqlToken, i int) bool {
+	return i > 0 && (tokens[i-1].is("FROM") || tokens[i-1].is("JOIN"))
+}
+
+// containsAny reports whether text contains one of the keys of m
+func containsAny(text string, m map[string]string) bool {
+	for name := range m {
+		if strings.Contains(text, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdentifierWord reports whether an unquoted word can be an identifier, i.e. is not a number
+func isIdentifierWord(word string) bool {
+	return strings.TrimLeft(word, "0123456789") != ""
+}
+
+// mappedDatabases returns the target databases of m, sorted
+func mappedDatabases(m map[string]string) []string {
+	seen := make(map[string]bool, len(m))
+	var targets []string
+	for _, target := range m {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
@@ -0,0 +1,89 @@
+package mysqldump
+
+import "testing"
+
+func Test_remapDatabases(t *testing.T) {
+	m := map[string]string{"prod_orders": "staging_orders"}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "use", text: "USE `prod_orders`", want: "USE `staging_orders`"},
+		{name: "unquoted use", text: "USE prod_orders", want: "USE `staging_orders`"},
+		{
+			name: "create database",
+			text: "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `prod_orders` /*!40100 DEFAULT CHARACTER SET utf8mb4 */",
+			want: "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `staging_orders` /*!40100 DEFAULT CHARACTER SET utf8mb4 */",
+		},
+		{
+			name: "qualified identifiers",
+			text: "CREATE VIEW `v` AS select `prod_orders`.`o`.`id` AS `id` from `prod_orders`.`o` join prod_orders.c",
+			want: "CREATE VIEW `v` AS select `staging_orders`.`o`.`id` AS `id` from `staging_orders`.`o` join `staging_orders`.c",
+		},
+		{
+			name: "strings are kept",
+			text: "INSERT INTO `prod_orders`.`o` VALUES ('prod_orders.x','`prod_orders`.')",
+			want: "INSERT INTO `staging_orders`.`o` VALUES ('prod_orders.x','`prod_orders`.')",
+		},
+		{
+			name: "table named like the database",
+			text: "CREATE VIEW `v` AS select `prod_orders`.`id` AS `id` from `prod_orders` join `prod_orders`.`c` on `prod_orders`.`id` = `c`.`oid`",
+			want: "CREATE VIEW `v` AS select `prod_orders`.`id` AS `id` from `prod_orders` join `staging_orders`.`c` on `prod_orders`.`id` = `c`.`oid`",
+		},
+		{
+			name: "trigger",
+			text: "CREATE TRIGGER `tr` BEFORE INSERT ON `prod_orders`.`o` FOR EACH ROW SET NEW.`prod_orders` = 1",
+			want: "CREATE TRIGGER `tr` BEFORE INSERT ON `staging_orders`.`o` FOR EACH ROW SET NEW.`prod_orders` = 1",
+		},
+		{
+			name: "foreign key",
+			text: "CREATE TABLE `c` (`oid` int, CONSTRAINT `fk` FOREIGN KEY (`oid`) REFERENCES `prod_orders`.`o` (`id`))",
+			want: "CREATE TABLE `c` (`oid` int, CONSTRAINT `fk` FOREIGN KEY (`oid`) REFERENCES `staging_orders`.`o` (`id`))",
+		},
+		{
+			name: "rename",
+			text: "RENAME TABLE `prod_orders`.`o` TO `prod_orders`.`o2`",
+			want: "RENAME TABLE `staging_orders`.`o` TO `staging_orders`.`o2`",
+		},
+		{
+			name: "drop table list",
+			text: "DROP TABLE IF EXISTS prod_orders.t, prod_orders.u",
+			want: "DROP TABLE IF EXISTS `staging_orders`.t, `staging_orders`.u",
+		},
+		{
+			name: "lock tables list",
+			text: "LOCK TABLES prod_orders.a WRITE, prod_orders.b WRITE",
+			want: "LOCK TABLES `staging_orders`.a WRITE, `staging_orders`.b WRITE",
+		},
+		{
+			name: "rename table list",
+			text: "RENAME TABLE prod_orders.a TO prod_orders.b, prod_orders.c TO prod_orders.d",
+			want: "RENAME TABLE `staging_orders`.a TO `staging_orders`.b, `staging_orders`.c TO `staging_orders`.d",
+		},
+		{
+			name: "from list",
+			text: "CREATE VIEW `v` AS select `o`.`id` AS `id` from (`prod_orders`.`o`, `prod_orders`.`c`) where `o`.`id` = `c`.`oid`",
+			want: "CREATE VIEW `v` AS select `o`.`id` AS `id` from (`staging_orders`.`o`, `staging_orders`.`c`) where `o`.`id` = `c`.`oid`",
+		},
+		{
+			name: "join of a view",
+			text: "CREATE VIEW `v` AS select `o`.`id` AS `id` from (`prod_orders`.`o` join `prod_orders`.`c` on((`prod_orders`.`id` = `c`.`oid`)))",
+			want: "CREATE VIEW `v` AS select `o`.`id` AS `id` from (`staging_orders`.`o` join `staging_orders`.`c` on((`prod_orders`.`id` = `c`.`oid`)))",
+		},
+		{
+			name: "select list",
+			text: "CREATE VIEW `v` AS select `prod_orders`.`id` AS `id`, `prod_orders`.`n` AS `n`, concat(`a`, `prod_orders`.`n`) AS `c` from `prod_orders`",
+			want: "CREATE VIEW `v` AS select `prod_orders`.`id` AS `id`, `prod_orders`.`n` AS `n`, concat(`a`, `prod_orders`.`n`) AS `c` from `prod_orders`",
+		},
+		{name: "other databases", text: "USE `prod_users`", want: "USE `prod_users`"},
+		{name: "table with the same name", text: "INSERT INTO `prod_orders` VALUES (1)", want: "INSERT INTO `prod_orders` VALUES (1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remapDatabases(tt.text, m); got != tt.want {
+				t.Errorf("remapDatabases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
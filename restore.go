@@ -0,0 +1,526 @@
+package mysqldump
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// maximum length of a statement kept in a StatementFailure
+const maxFailureStatement = 1024
+
+type (
+	// RestoreReport summarizes a Source run
+	RestoreReport struct {
+		// Statements successfully executed
+		Statements int
+		// Failures of the statements skipped by WithContinueOnError
+		Failures []StatementFailure
+		// Plan of the statements that would run, only with WithDryRun
+		Plan *RestorePlan
+		// LastCommit is the input position after the last statement that committed,
+		// with COMMIT or implicitly as CREATE TABLE and UNLOCK TABLES do.
+		// Everything before it is applied to the database, except with
+		// WithRestoreParallelism and WithShadowRestore where only the end of the restore is
+		LastCommit RestorePosition
+	}
+
+	// RestorePosition is a position in the Source input between two statements
+	RestorePosition struct {
+		// Offset is the number of input bytes consumed
+		Offset int64
+		// Statement is the number of statements read from the input
+		Statement int
+		// Line is the 1-based line number at Offset
+		Line int
+		// Delimiter in effect at this position
+		Delimiter string
+	}
+
+	// StatementFailure describes a statement that failed during Source
+	StatementFailure struct {
+		// Offset is the byte offset of the statement in the input
+		Offset int64
+		// Line is the 1-based line number the statement starts on
+		Line int
+		// Database selected when the statement ran
+		Database string
+		// Table the statement targets, empty if unknown
+		Table string
+		// Code is the MySQL server error number, 0 if the error did not come from the server
+		Code uint16
+		// Statement text, truncated to 1024 bytes
+		Statement string
+		Err       error
+	}
+)
+
+func (f StatementFailure) Error() string {
+	var target string
+	if f.Table != "" {
+		target = fmt.Sprintf(" (table %s)", f.Table)
+	}
+	return fmt.Sprintf("line %d, offset %d%s: %v", f.Line, f.Offset, target, f.Err)
+}
+
+func (f StatementFailure) Unwrap() error {
+	return f.Err
+}
+
+// Failed reports whether any statement failed
+func (r *RestoreReport) Failed() bool {
+	return len(r.Failures) > 0
+}
+
+// Err returns the failures joined in a single error, or nil if there are none
+func (r *RestoreReport) Err() error {
+	errs := make([]error, len(r.Failures))
+	for i, f := range r.Failures {
+		errs[i] = f
+	}
+	return errors.Join(errs...)
+}
+
+// restorer executes the statements read by a StatementScanner on one session.
+// Workers of a parallel restore have no scanner and are fed statements directly.
+type restorer struct {
+	o      *sourceOption
+	db     *dbWrapper
+	sc     *StatementScanner
+	report *RestoreReport
+
+	// database selected by the last USE statement
+	database string
+	// same, named as in the input before WithDatabaseMap
+	srcDatabase string
+	// INSERT statements waiting to be merged, starting at statement first
+	inserts []string
+	first   Statement
+
+	// statements read from the input
+	read int
+	// statements and bytes executed since the last COMMIT
+	uncommitted      int
+	uncommittedBytes int64
+	// the last statement executed committed implicitly and its position is not recorded yet
+	implicitCommit bool
+
+	// checkpoint written after each commit, nil if disabled
+	cp *checkpointer
+	// SET and USE statements executed so far, replayed when resuming
+	session []string
+	// statements already applied by a previous run, read but not executed
+	skip int
+
+	// dump section of the current statement
+	section section
+	// character set the input is read in
+	charset clientCharset
+	// loads data sections concurrently, nil without WithRestoreParallelism
+	par *parallelLoader
+
+	// opens a new session when the connection is lost
+	open func() (*dbWrapper, error)
+	// statements executed since the last commit, replayed by WithRetry
+	batch []string
+
+	// statements run once all data is loaded
+	deferred []deferredStatement
+	// loads the tables into shadow copies, nil without WithShadowRestore
+	shadow *shadowRestore
+	// table of the last statement
+	table string
+	// reports the progress, nil without WithProgress
+	progress *progressTracker
+}
+
+// deferredStatement is a statement run after all data is loaded
+type deferredStatement struct {
+	database string
+	stmt     Statement
+}
+
+func newRestorer(o *sourceOption, db *dbWrapper, sc *StatementScanner, database string) *restorer {
+	r := &restorer{
+		o:           o,
+		db:          db,
+		sc:          sc,
+		report:      &RestoreReport{},
+		database:    database,
+		srcDatabase: database,
+	}
+	if o.dryRun {
+		r.report.Plan = &RestorePlan{}
+	}
+	return r
+}
+
+// run executes every statement of the input
+func (r *restorer) run() error {
+	for r.sc.Scan() {
+		r.read++
+		if r.read <= r.skip {
+			r.follow(r.sc.Statement().Text)
+			continue
+		}
+
+		if err := r.handle(r.sc.Statement()); err != nil {
+			return err
+		}
+		if r.commitDue() {
+			if err := r.commit(); err != nil {
+				return err
+			}
+		} else if r.implicitlyCommitted() {
+			if err := r.committed(); err != nil {
+				return err
+			}
+		}
+		if r.progress != nil {
+			r.progress.report(r, false)
+		}
+	}
+	if err := r.sc.Err(); err != nil {
+		r.o.logger.Error("cannot read the input", "phase", PhaseRead, "err", err)
+		return &SourceError{Phase: PhaseRead, Database: r.database, Offset: r.sc.Offset(), Err: err}
+	}
+	if err := r.commit(); err != nil {
+		return err
+	}
+	if r.par != nil {
+		if err := r.par.finish(r); err != nil {
+			return err
+		}
+	}
+	if r.shadow != nil {
+		if err := r.shadow.swap(r); err != nil {
+			return err
+		}
+	}
+	if err := r.runDeferred(); err != nil {
+		return err
+	}
+	if r.progress != nil {
+		r.progress.report(r, true)
+	}
+	if r.cp != nil && !r.o.dryRun {
+		return r.cp.remove()
+	}
+	return nil
+}
+
+// handle runs a statement read from the input, unless it is filtered out
+// or belongs to another session
+func (r *restorer) handle(stmt Statement) error {
+	for _, c := range stmt.Comments {
+		if sec, ok := parseSectionMarker(c); ok {
+			r.section = sec
+		}
+	}
+	info := parseStatement(stmt.Text)
+	if (r.section.kind == sectionView || r.section.kind == sectionTrigger) && !r.section.holds(info) {
+		r.section = section{}
+	}
+	// Dump ends each data section by unlocking the table
+	defer func() {
+		if r.section.kind == sectionData && info.verb == "UNLOCK" {
+			r.section = section{}
+		}
+	}()
+
+	if info.verb == "USE" {
+		r.srcDatabase = info.database
+		r.section = section{}
+	}
+	if r.section.table != "" {
+		r.table = r.section.table
+	} else if info.table != "" {
+		r.table = info.table
+	}
+	if !r.selected(info) {
+		return nil
+	}
+	if len(r.o.databaseMap) > 0 {
+		stmt.Text = remapDatabases(stmt.Text, r.o.databaseMap)
+	}
+	if len(r.o.rewriters) == 0 {
+		return r.dispatch(stmt, info)
+	}
+
+	stmts, err := r.rewrite(stmt)
+	if err != nil {
+		return r.fail(PhaseRewrite, stmt, stmt.Text, info, err)
+	}
+	for _, s := range stmts {
+		if err = r.dispatch(s, parseStatement(s.Text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatch sends stmt to the parallel loader, or runs it on this session
+func (r *restorer) dispatch(stmt Statement, info statementInfo) error {
+	if r.shadow != nil {
+		deferred, err := r.shadow.prepare(r, stmt, info)
+		if err != nil || deferred {
+			return err
+		}
+	}
+	if r.par != nil {
+		routed, err := r.par.route(r, stmt, info)
+		if err != nil || routed {
+			return err
+		}
+	}
+	return r.statement(stmt)
+}
+
+// runDeferred runs the statements put off until all data is loaded, then commits
+func (r *restorer) runDeferred() error {
+	if len(r.deferred) == 0 {
+		return nil
+	}
+	for _, d := range r.deferred {
+		if d.database != r.database && d.database != "" {
+			if err := r.exec(Statement{}, "USE "+quoteIdentifier(d.database)); err != nil {
+				return err
+			}
+		}
+		if err := r.statement(d.stmt); err != nil {
+			return err
+		}
+	}
+	return r.commit()
+}
+
+// resume continues a restore from a checkpoint: the session state is restored
+// and, unless the input was moved to the checkpoint, the applied statements are skipped
+func (r *restorer) resume(state *restoreCheckpoint, seeked bool) error {
+	if seeked {
+		r.sc.resume(state.RestorePosition)
+		r.read = state.Statement
+		if r.progress != nil {
+			r.progress.startOffset = state.Offset
+		}
+	} else {
+		r.skip = state.Statement
+	}
+	r.report.LastCommit = state.RestorePosition
+	// the filters of the statements after the position depend on them
+	if state.Database != "" {
+		r.srcDatabase = state.Database
+	}
+	r.section = section{kind: state.Section, table: state.SectionTable}
+
+	for _, ssql := range state.Session {
+		if err := r.exec(Statement{}, ssql); err != nil {
+			return err
+		}
+		r.follow(ssql)
+	}
+	r.o.logger.Info("resuming", "phase", PhaseCheckpoint, "statement", state.Statement, "offset", state.Offset)
+	return nil
+}
+
+// remember keeps a session statement for checkpoints,
+// dropping an earlier identical one to keep the list short
+func (r *restorer) remember(ssql string) {
+	for i, s := range r.session {
+		if s == ssql {
+			r.session = append(r.session[:i], r.session[i+1:]...)
+			break
+		}
+	}
+	r.session = append(r.session, ssql)
+}
+
+// commitDue reports whether WithCommitEvery thresholds are reached
+func (r *restorer) commitDue() bool {
+	return r.o.commitStatements > 0 && r.uncommitted >= r.o.commitStatements ||
+		r.o.commitBytes > 0 && r.uncommittedBytes >= r.o.commitBytes
+}
+
+// commit executes the pending statements and commits them,
+// recording the input position reached
+func (r *restorer) commit() error {
+	if err := r.flush(); err != nil {
+		return err
+	}
+	if err := r.execRetry("COMMIT;"); err != nil {
+		r.o.logger.Error("commit failed", "phase", PhaseCommit, "db", r.database, "err", err)
+		return &SourceError{Phase: PhaseCommit, Database: r.database, Err: err}
+	}
+	return r.committed()
+}
+
+// implicitlyCommitted reports whether the last statement executed committed
+// everything read so far, as DDL and LOCK TABLES do.
+// Data and deferred statements run out of input order with
+// WithRestoreParallelism and WithShadowRestore, only COMMIT counts there.
+func (r *restorer) implicitlyCommitted() bool {
+	return r.implicitCommit && len(r.inserts) == 0 && r.par == nil && r.shadow == nil
+}
+
+// committed records that the statements executed so far are committed,
+// saving the input position reached
+func (r *restorer) committed() error {
+	r.uncommitted, r.uncommittedBytes = 0, 0
+	r.batch = r.batch[:0]
+	r.implicitCommit = false
+	if r.sc == nil {
+		return nil
+	}
+	r.report.LastCommit = RestorePosition{
+		Offset:    r.sc.Offset(),
+		Statement: r.read,
+		Line:      r.sc.line,
+		Delimiter: r.sc.Delimiter(),
+	}
+	if r.cp != nil && !r.o.dryRun {
+		if err := r.cp.save(restoreCheckpoint{
+			RestorePosition: r.report.LastCommit,
+			Session:         r.session,
+			Database:        r.srcDatabase,
+			Section:         r.section.kind,
+			SectionTable:    r.section.table,
+		}); err != nil {
+			r.o.logger.Error("cannot save the checkpoint", "phase", PhaseCheckpoint, "err", err)
+			return &SourceError{Phase: PhaseCheckpoint, Offset: r.report.LastCommit.Offset, Line: r.report.LastCommit.Line, Err: err}
+		}
+	}
+	return nil
+}
+
+// statement executes stmt, or queues it when INSERTs are merged
+func (r *restorer) statement(stmt Statement) error {
+	if r.shadow != nil {
+		stmt.Text = r.shadow.rename(stmt.Text, r.database)
+	}
+	if r.o.mergeInsert > 1 && strings.HasPrefix(stmt.Text, "INSERT INTO") {
+		// only INSERTs into the same table and columns can be merged
+		if len(r.inserts) > 0 && insertTarget(r.inserts[0]) != insertTarget(stmt.Text) {
+			if err := r.flush(); err != nil {
+				return err
+			}
+		}
+		if len(r.inserts) == 0 {
+			r.first = stmt
+		}
+		r.inserts = append(r.inserts, stmt.Text)
+		if len(r.inserts) >= r.o.mergeInsert {
+			return r.flush()
+		}
+		return nil
+	}
+	if err := r.flush(); err != nil {
+		return err
+	}
+
+	if err := r.exec(stmt, stmt.Text); err != nil {
+		return err
+	}
+	r.follow(stmt.Text)
+	return nil
+}
+
+// follow tells the scanner how the server reads the statements after text:
+// whether backslash escapes characters, and in which character set
+func (r *restorer) follow(text string) {
+	if r.sc == nil {
+		return
+	}
+	if noBackslash, ok := sqlModeEscapes(text); ok {
+		r.sc.SetNoBackslashEscapes(noBackslash)
+	}
+	if r.charset.follow(text) {
+		r.sc.SetCharset(r.charset.current)
+	}
+}
+
+// flush executes the pending INSERT statements as a single one
+func (r *restorer) flush() error {
+	if len(r.inserts) == 0 {
+		return nil
+	}
+	ssql, err := mergeInsert(r.inserts)
+	r.inserts = r.inserts[:0]
+	if err != nil {
+		r.o.logger.Error("cannot merge inserts", "phase", PhaseExec, "db", r.database, "table", r.table, "err", err)
+		return &SourceError{Phase: PhaseExec, Database: r.database, Table: r.table, Offset: r.first.Offset, Line: r.first.Line, Err: err}
+	}
+	return r.exec(r.first, ssql)
+}
+
+// exec runs query, read at the position of stmt.
+// Failures are recorded instead of returned when continuing on errors.
+func (r *restorer) exec(stmt Statement, query string) error {
+	info := parseStatement(query)
+
+	if err := r.execRetry(query); err != nil {
+		return r.fail(PhaseExec, stmt, query, info, err)
+	}
+
+	r.report.Statements++
+	if r.implicitCommit = commitsImplicitly(info.verb); r.implicitCommit {
+		// the server committed the transaction along with the statement
+		r.uncommitted, r.uncommittedBytes = 0, 0
+	} else {
+		r.uncommitted++
+		r.uncommittedBytes += int64(len(query))
+	}
+	r.trackBatch(query, info)
+	r.plan(stmt, query, info)
+	if r.progress != nil && stmt.Line > 0 {
+		r.progress.executed(query, info)
+	}
+	switch info.verb {
+	case "USE":
+		r.database = info.database
+		r.remember(query)
+	case "SET":
+		r.remember(query)
+	}
+	return nil
+}
+
+// fail records the failure of query, read at the position of stmt.
+// It returns a SourceError unless continuing on errors.
+func (r *restorer) fail(phase Phase, stmt Statement, query string, info statementInfo, err error) error {
+	failure := StatementFailure{
+		Offset:    stmt.Offset,
+		Line:      stmt.Line,
+		Database:  r.database,
+		Table:     info.table,
+		Statement: abbreviate(query, maxFailureStatement),
+		Err:       err,
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		failure.Code = myErr.Number
+	}
+	r.o.logger.Error("statement failed", "phase", phase, "db", failure.Database, "table", failure.Table,
+		"line", failure.Line, "offset", failure.Offset, "code", failure.Code, "err", err)
+	if !r.o.continueOnError {
+		return &SourceError{
+			Phase:    phase,
+			Database: failure.Database,
+			Table:    failure.Table,
+			Offset:   failure.Offset,
+			Line:     failure.Line,
+			Err:      err,
+		}
+	}
+	r.report.Failures = append(r.report.Failures, failure)
+	return nil
+}
+
+// abbreviate truncates s to n bytes
+func abbreviate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
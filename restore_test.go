@@ -0,0 +1,235 @@
+package mysqldump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newDryRunRestorer returns a restorer executing nothing, with database selected
+func newDryRunRestorer(database string, opts ...SourceOption) *restorer {
+	o := newSourceOption(append(opts, WithDryRun()))
+	w := newDBWrapper(context.Background(), nil, nil, true, o.logger)
+	return newRestorer(o, w, nil, database)
+}
+
+func Test_restorer_commitDue(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements int
+		bytes      int64
+		want       bool
+	}{
+		{name: "below both limits", statements: 9, bytes: 999, want: false},
+		{name: "statement limit", statements: 10, bytes: 1, want: true},
+		{name: "byte limit", statements: 1, bytes: 1000, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newDryRunRestorer("db", WithCommitEvery(10, 1000))
+			r.uncommitted, r.uncommittedBytes = tt.statements, tt.bytes
+			if got := r.commitDue(); got != tt.want {
+				t.Errorf("commitDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// zero disables a limit
+	r := newDryRunRestorer("db", WithCommitEvery(0, 1000))
+	r.uncommitted = 1 << 20
+	if r.commitDue() {
+		t.Error("commitDue() = true without a statement limit")
+	}
+	r = newDryRunRestorer("db")
+	r.uncommitted, r.uncommittedBytes = 1<<20, 1<<30
+	if r.commitDue() {
+		t.Error("commitDue() = true without WithCommitEvery")
+	}
+}
+
+func Test_restorer_commit(t *testing.T) {
+	r := newDryRunRestorer("db", WithCommitEvery(2, 0))
+	const input = "USE `db`;\nINSERT INTO `t` VALUES (1);\nINSERT INTO `t` VALUES (2);\nINSERT INTO `t` VALUES (3);\n"
+	r.sc = NewStatementScanner(strings.NewReader(input))
+
+	// commits after the second statement
+	for i := 0; i < 2 && r.sc.Scan(); i++ {
+		r.read++
+		if err := r.statement(r.sc.Statement()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !r.commitDue() {
+		t.Fatal("commitDue() = false after two statements")
+	}
+	if err := r.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if r.uncommitted != 0 || r.uncommittedBytes != 0 {
+		t.Errorf("uncommitted = %d statements, %d bytes after commit", r.uncommitted, r.uncommittedBytes)
+	}
+	wantPos := RestorePosition{Offset: int64(strings.Index(input, ";\nINSERT INTO `t` VALUES (2)") + 1), Statement: 2, Line: 2, Delimiter: ";"}
+	if r.report.LastCommit != wantPos {
+		t.Errorf("LastCommit = %+v, want %+v", r.report.LastCommit, wantPos)
+	}
+
+	// then at the end of the input
+	if err := r.run(); err != nil {
+		t.Fatal(err)
+	}
+	wantPos = RestorePosition{Offset: int64(len(input)), Statement: 4, Line: 5, Delimiter: ";"}
+	if r.report.LastCommit != wantPos {
+		t.Errorf("LastCommit = %+v, want %+v", r.report.LastCommit, wantPos)
+	}
+}
+
+func Test_restorer_commit_flush(t *testing.T) {
+	r := newDryRunRestorer("db", WithMergeInsert(10))
+	r.sc = NewStatementScanner(strings.NewReader(""))
+	for _, text := range []string{"INSERT INTO `t` VALUES (1)", "INSERT INTO `t` VALUES (2)"} {
+		if err := r.statement(Statement{Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(r.inserts) != 2 {
+		t.Fatalf("inserts = %q, want both INSERTs waiting to be merged", r.inserts)
+	}
+	if err := r.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the merged INSERTs are flushed before the COMMIT
+	if len(r.inserts) != 0 {
+		t.Errorf("inserts = %q after commit, want none", r.inserts)
+	}
+}
+
+func Test_restorer_commit_checkpoint(t *testing.T) {
+	q := &scriptedQuerier{}
+	o := newSourceOption([]SourceOption{WithCommitEvery(1, 0)})
+	r := newRestorer(o, newDBWrapper(context.Background(), q, nil, false, o.logger), nil, "db")
+	path := filepath.Join(t.TempDir(), "restore.checkpoint")
+	r.cp = &checkpointer{path: path}
+
+	r.sc = NewStatementScanner(strings.NewReader("USE `db`;\nINSERT INTO `t` VALUES (1);\n"))
+	for i := 0; i < 2 && r.sc.Scan(); i++ {
+		r.read++
+		if err := r.handle(r.sc.Statement()); err != nil {
+			t.Fatal(err)
+		}
+		if r.commitDue() {
+			if err := r.commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state restoreCheckpoint
+	if err = json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Statement != 2 || state.Database != "db" || !reflect.DeepEqual(state.Session, []string{"USE `db`"}) {
+		t.Errorf("checkpoint = %+v, want statement 2 in db", state)
+	}
+}
+
+// two tables with their data, as written by Dump
+const dataDump = "USE `db`;\n" +
+	"CREATE TABLE `a` (`x` int);\n" +
+	"-- ----------------------------\n-- Dumping data for table a\n-- ----------------------------\n" +
+	"LOCK TABLES `a` WRITE;\n" +
+	"INSERT INTO `a` VALUES (1);\n" +
+	"INSERT INTO `a` VALUES (2);\n" +
+	"UNLOCK TABLES;\n" +
+	"CREATE TABLE `b` (`x` int);\n" +
+	"-- ----------------------------\n-- Dumping data for table b\n-- ----------------------------\n" +
+	"LOCK TABLES `b` WRITE;\n" +
+	"INSERT INTO `b` VALUES (1);\n" +
+	"UNLOCK TABLES;\n"
+
+func Test_restorer_implicitCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "restore.checkpoint")
+	broken := errors.New("connection reset")
+	q := &scriptedQuerier{fail: map[string][]error{"INSERT INTO `b` VALUES (1)": {broken}}}
+	o := newSourceOption(nil)
+	r := newRestorer(o, newDBWrapper(context.Background(), q, nil, false, o.logger), NewStatementScanner(strings.NewReader(dataDump)), "db")
+	r.cp = &checkpointer{path: path}
+	if err := r.run(); !errors.Is(err, broken) {
+		t.Fatalf("run() error = %v, want %v", err, broken)
+	}
+
+	// LOCK TABLES `b` committed everything before the failing INSERT
+	offset := int64(strings.Index(dataDump, "LOCK TABLES `b` WRITE;") + len("LOCK TABLES `b` WRITE;"))
+	wantPos := RestorePosition{Offset: offset, Statement: 8, Line: 14, Delimiter: ";"}
+	if r.report.LastCommit != wantPos {
+		t.Errorf("LastCommit = %+v, want %+v", r.report.LastCommit, wantPos)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state restoreCheckpoint
+	if err = json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.RestorePosition != wantPos || state.Section != sectionData || state.SectionTable != "b" {
+		t.Fatalf("checkpoint = %+v, want %+v in the data of b", state, wantPos)
+	}
+
+	// resuming runs the rest of the data of b only
+	q = &scriptedQuerier{}
+	r = newRestorer(o, newDBWrapper(context.Background(), q, nil, false, o.logger), NewStatementScanner(strings.NewReader(dataDump)), "db")
+	if err = r.resume(&state, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"USE `db`", "INSERT INTO `b` VALUES (1)", "UNLOCK TABLES", "COMMIT;"}
+	if !reflect.DeepEqual(q.execs, want) {
+		t.Errorf("executed = %q\nwant %q", q.execs, want)
+	}
+}
+
+func Test_restorer_implicitCommit_pending(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []SourceOption
+		want  int
+	}{
+		{name: "statement after the DDL", input: "CREATE TABLE `a` (`x` int);\nINSERT INTO `a` VALUES (1);\n", want: 1},
+		{name: "merged INSERT waiting", input: "CREATE TABLE `a` (`x` int);\nINSERT INTO `a` VALUES (1);\n", opts: []SourceOption{WithMergeInsert(10)}, want: 1},
+		{name: "DDL after the statement", input: "INSERT INTO `a` VALUES (1);\nDROP TABLE `b`;\n", want: 2},
+		{name: "filtered statements", input: "DROP TABLE `a`;\nINSERT INTO `b` VALUES (1);\n", opts: []SourceOption{WithOnlyTables("a")}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newDryRunRestorer("db", tt.opts...)
+			r.sc = NewStatementScanner(strings.NewReader(tt.input))
+			for r.sc.Scan() {
+				r.read++
+				if err := r.handle(r.sc.Statement()); err != nil {
+					t.Fatal(err)
+				}
+				if r.implicitlyCommitted() {
+					if err := r.committed(); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if r.report.LastCommit.Statement != tt.want {
+				t.Errorf("LastCommit = %+v, want statement %d", r.report.LastCommit, tt.want)
+			}
+		})
+	}
+}
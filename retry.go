@@ -0,0 +1,146 @@
+package mysqldump
+
+import (
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// RetryPolicy tells Source which errors are transient and how to retry them.
+// The transaction in progress is rolled back and its statements replayed,
+// so they are kept in memory until the next commit (see WithCommitEvery).
+// Fields left to zero take their value from DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a statement is tried, including the first one
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled after each attempt up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// ErrorNumbers are the MySQL errors retried, dropped connections are always retried
+	ErrorNumbers []uint16
+}
+
+// DefaultRetryPolicy retries deadlocks (1213) and lock wait timeouts (1205)
+// up to 5 times, waiting from 100ms to 10s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		ErrorNumbers:   []uint16{1205, 1213},
+	}
+}
+
+// retriable reports whether err is worth retrying
+func (p *RetryPolicy) retriable(err error) bool {
+	if isConnError(err) {
+		return true
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+	for _, n := range p.ErrorNumbers {
+		if myErr.Number == n {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the given retry, starting at 1
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// isConnError reports whether err means the connection was lost
+func isConnError(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn)
+}
+
+// execRetry runs query, retrying transient errors as configured by WithRetry
+func (r *restorer) execRetry(query string) error {
+	policy := r.o.retry
+	if policy != nil && commitsImplicitly(parseStatement(query).verb) {
+		// the transaction in progress is committed before the statement runs,
+		// even when it fails: it must not be replayed
+		r.batch = r.batch[:0]
+	}
+	_, err := r.db.Exec(query)
+	if err == nil || policy == nil {
+		return err
+	}
+
+	for attempt := 1; attempt < policy.MaxAttempts && policy.retriable(err); attempt++ {
+		wait := policy.backoff(attempt)
+		r.o.logger.Warn("retrying", "phase", PhaseExec, "db", r.database, "table", r.table,
+			"attempt", attempt+1, "max_attempts", policy.MaxAttempts, "backoff", wait, "err", err)
+		select {
+		case <-r.db.ctx.Done():
+			return r.db.ctx.Err()
+		case <-time.After(wait):
+		}
+
+		if err = r.replay(err); err != nil {
+			continue
+		}
+		_, err = r.db.Exec(query)
+	}
+	return err
+}
+
+// replay rolls back the transaction in progress, or reconnects when the connection
+// was lost, and runs again the statements executed since the last commit
+func (r *restorer) replay(cause error) error {
+	if isConnError(cause) && r.open != nil {
+		if r.db.release != nil {
+			r.db.release()
+		}
+		w, err := r.open()
+		if err != nil {
+			return err
+		}
+		r.db = w
+		for _, ssql := range r.session {
+			if _, err = r.db.Exec(ssql); err != nil {
+				return err
+			}
+		}
+	} else if _, err := r.db.Exec("ROLLBACK;"); err != nil {
+		return err
+	}
+
+	for _, ssql := range r.batch {
+		if _, err := r.db.Exec(ssql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackBatch keeps the statements of the transaction in progress for replay.
+// Statements causing an implicit commit start a new transaction, see execRetry.
+func (r *restorer) trackBatch(query string, info statementInfo) {
+	if r.o.retry == nil || commitsImplicitly(info.verb) {
+		return
+	}
+	r.batch = append(r.batch, query)
+}
+
+// commitsImplicitly reports whether a statement commits the transaction in progress
+func commitsImplicitly(verb string) bool {
+	switch verb {
+	case "CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE", "LOCK", "UNLOCK", "COMMIT":
+		return true
+	}
+	return false
+}
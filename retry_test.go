@@ -0,0 +1,167 @@
+package mysqldump
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestRetryPolicy_retriable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "deadlock", err: &mysql.MySQLError{Number: 1213}, want: true},
+		{name: "lock wait timeout", err: &mysql.MySQLError{Number: 1205}, want: true},
+		{name: "wrapped", err: fmt.Errorf("exec: %w", &mysql.MySQLError{Number: 1213}), want: true},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "invalid connection", err: mysql.ErrInvalidConn, want: true},
+		{name: "duplicate key", err: &mysql.MySQLError{Number: 1062}, want: false},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.retriable(tt.err); got != tt.want {
+				t.Errorf("retriable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   RetryPolicy
+	}{
+		{name: "zero policy", policy: RetryPolicy{}, want: DefaultRetryPolicy()},
+		{
+			name:   "zero backoffs",
+			policy: RetryPolicy{MaxAttempts: 3, ErrorNumbers: []uint16{1062}},
+			want:   RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second, ErrorNumbers: []uint16{1062}},
+		},
+		{
+			name:   "negative values",
+			policy: RetryPolicy{MaxAttempts: -1, InitialBackoff: -time.Second, MaxBackoff: time.Minute},
+			want:   RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Minute, ErrorNumbers: []uint16{1205, 1213}},
+		},
+		{
+			name:   "complete policy",
+			policy: RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, MaxBackoff: time.Minute, ErrorNumbers: []uint16{1062}},
+			want:   RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, MaxBackoff: time.Minute, ErrorNumbers: []uint16{1062}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newSourceOption([]SourceOption{WithRetry(tt.policy)})
+			if !reflect.DeepEqual(*o.retry, tt.want) {
+				t.Errorf("WithRetry() policy = %+v, want %+v", *o.retry, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{retry: 1, want: 100 * time.Millisecond},
+		{retry: 2, want: 200 * time.Millisecond},
+		{retry: 4, want: 800 * time.Millisecond},
+		{retry: 5, want: time.Second},
+		{retry: 50, want: time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.backoff(tt.retry); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.retry, got, tt.want)
+		}
+	}
+}
+
+// scriptedQuerier records the statements executed and fails them as scripted
+type scriptedQuerier struct {
+	execs []string
+	// errors returned by the next executions of a statement
+	fail map[string][]error
+}
+
+func (q *scriptedQuerier) ExecContext(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+	q.execs = append(q.execs, query)
+	if errs := q.fail[query]; len(errs) > 0 {
+		q.fail[query] = errs[1:]
+		return nil, errs[0]
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (q *scriptedQuerier) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (q *scriptedQuerier) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func Test_restorer_execRetry(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213}
+	tests := []struct {
+		name  string
+		stmts []string
+		fail  map[string][]error
+		want  []string
+	}{
+		{
+			name:  "transaction replayed",
+			stmts: []string{"INSERT INTO t VALUES (1)", "INSERT INTO t VALUES (2)"},
+			fail:  map[string][]error{"INSERT INTO t VALUES (2)": {deadlock}},
+			want: []string{
+				"INSERT INTO t VALUES (1)", "INSERT INTO t VALUES (2)",
+				"ROLLBACK;", "INSERT INTO t VALUES (1)", "INSERT INTO t VALUES (2)",
+			},
+		},
+		{
+			name:  "transaction committed by the failing DDL",
+			stmts: []string{"INSERT INTO t VALUES (1)", "CREATE TABLE u (a int)", "INSERT INTO u VALUES (1)"},
+			fail:  map[string][]error{"CREATE TABLE u (a int)": {deadlock}},
+			want: []string{
+				"INSERT INTO t VALUES (1)", "CREATE TABLE u (a int)",
+				"ROLLBACK;", "CREATE TABLE u (a int)", "INSERT INTO u VALUES (1)",
+			},
+		},
+		{
+			name:  "transaction committed by LOCK TABLES",
+			stmts: []string{"INSERT INTO t VALUES (1)", "LOCK TABLES u WRITE", "INSERT INTO u VALUES (1)"},
+			fail:  map[string][]error{"INSERT INTO u VALUES (1)": {deadlock}},
+			want: []string{
+				"INSERT INTO t VALUES (1)", "LOCK TABLES u WRITE", "INSERT INTO u VALUES (1)",
+				"ROLLBACK;", "INSERT INTO u VALUES (1)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &scriptedQuerier{fail: tt.fail}
+			o := newSourceOption([]SourceOption{WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})})
+			r := newRestorer(o, newDBWrapper(context.Background(), q, nil, false, o.logger), nil, "db")
+			for _, stmt := range tt.stmts {
+				if err := r.exec(Statement{}, stmt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(q.execs, tt.want) {
+				t.Errorf("executed = %q\nwant %q", q.execs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package mysqldump
+
+import (
+	"strings"
+)
+
+type (
+	// StatementRewriter changes the statements of the input before Source executes them.
+	// It returns the statement, possibly modified, several statements replacing it,
+	// or none to drop it. Rewriters see the statements after WithDatabaseMap.
+	StatementRewriter interface {
+		Rewrite(stmt Statement) ([]Statement, error)
+	}
+
+	// RewriterFunc adapts a function to a StatementRewriter
+	RewriterFunc func(stmt Statement) ([]Statement, error)
+)
+
+func (f RewriterFunc) Rewrite(stmt Statement) ([]Statement, error) {
+	return f(stmt)
+}
+
+// rewrite runs stmt through the rewriters of WithRewriter, in order
+func (r *restorer) rewrite(stmt Statement) ([]Statement, error) {
+	stmts := []Statement{stmt}
+	for _, rw := range r.o.rewriters {
+		var out []Statement
+		for _, s := range stmts {
+			res, err := rw.Rewrite(s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, res...)
+		}
+		stmts = out
+	}
+	return stmts, nil
+}
+
+// StripDefiner removes the DEFINER = user@host clause of views, triggers, routines and events,
+// so they can be created by a user without the SET_USER_ID or SUPER privilege
+func StripDefiner() StatementRewriter {
+	return RewriterFunc(func(stmt Statement) ([]Statement, error) {
+		if !isDDL(stmt.Text) || !containsFold(stmt.Text, "DEFINER") {
+			return []Statement{stmt}, nil
+		}
+		// only the clause, not a DEFINER column of a trigger or routine body
+		tokens := objectHeader(tokenize(stmt.Text, 32))
+		stmt.Text = splice(stmt.Text, tokens, func(i int) (int, string, bool) {
+			if !tokens[i].is("DEFINER") || i+1 >= len(tokens) || tokens[i+1].text != "=" {
+				return 0, "", false
+			}
+			end := skipAssignment(tokens, i)
+			// DEFINER = CURRENT_USER()
+			if end+1 < len(tokens) && tokens[end].text == "(" && tokens[end+1].text == ")" {
+				end += 2
+			}
+			// remove the space after the clause too
+			pos := tokens[end-1].end
+			for pos < len(stmt.Text) && isSpace(stmt.Text[pos]) {
+				pos++
+			}
+			return pos, "", true
+		})
+		return []Statement{stmt}, nil
+	})
+}
+
+// ReplaceEngine replaces the storage engine from (e.g. MyISAM) by to (e.g. InnoDB)
+// in CREATE TABLE and ALTER TABLE statements
+func ReplaceEngine(from, to string) StatementRewriter {
+	return RewriterFunc(func(stmt Statement) ([]Statement, error) {
+		if !isDDL(stmt.Text) || !containsFold(stmt.Text, from) {
+			return []Statement{stmt}, nil
+		}
+		tokens := tokenize(stmt.Text, -1)
+		stmt.Text = splice(stmt.Text, tokens, func(i int) (int, string, bool) {
+			if !tokens[i].is("ENGINE") {
+				return 0, "", false
+			}
+			j := i + 1
+			if j < len(tokens) && tokens[j].text == "=" {
+				j++
+			}
+			if j >= len(tokens) || !tokens[j].is(from) && !(tokens[j].ident && strings.EqualFold(tokens[j].text, from)) {
+				return 0, "", false
+			}
+			return tokens[j].end, stmt.Text[tokens[i].start:tokens[j].start] + to, true
+		})
+		return []Statement{stmt}, nil
+	})
+}
+
+// ReplaceCollation replaces the collation from (e.g. utf8mb4_0900_ai_ci) by to
+// (e.g. utf8mb4_general_ci) in table definitions and session variables.
+// Row data is left untouched.
+func ReplaceCollation(from, to string) StatementRewriter {
+	return RewriterFunc(func(stmt Statement) ([]Statement, error) {
+		if isInsert(stmt.Text) || !containsFold(stmt.Text, from) {
+			return []Statement{stmt}, nil
+		}
+		tokens := tokenize(stmt.Text, -1)
+		stmt.Text = splice(stmt.Text, tokens, func(i int) (int, string, bool) {
+			switch tok := tokens[i]; {
+			case tok.is(from):
+				return tok.end, to, true
+			case tok.str && len(tok.text) > 1 && strings.EqualFold(tok.text[1:len(tok.text)-1], from):
+				// SET collation_connection = 'x'
+				return tok.end, tok.text[:1] + to + tok.text[:1], true
+			}
+			return 0, "", false
+		})
+		return []Statement{stmt}, nil
+	})
+}
+
+// objectHeader returns the tokens of a CREATE or ALTER statement before the kind of object,
+// where its DEFINER and SQL SECURITY clauses are
+func objectHeader(tokens []sqlToken) []sqlToken {
+	for i, tok := range tokens {
+		if tok.is("VIEW") || tok.is("TRIGGER") || tok.is("PROCEDURE") || tok.is("FUNCTION") || tok.is("EVENT") {
+			return tokens[:i]
+		}
+	}
+	return tokens
+}
+
+// splice rewrites text: for each token, match returns whether the text from the token
+// up to end is replaced by repl. Tokens before end are then skipped.
+func splice(text string, tokens []sqlToken, match func(i int) (end int, repl string, ok bool)) string {
+	var (
+		sb   strings.Builder
+		last int
+	)
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].start < last {
+			continue
+		}
+		end, repl, ok := match(i)
+		if !ok {
+			continue
+		}
+		sb.WriteString(text[last:tokens[i].start])
+		sb.WriteString(repl)
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// isDDL reports whether text creates or alters an object
+func isDDL(text string) bool {
+	switch parseStatement(text).verb {
+	case "CREATE", "ALTER":
+		return true
+	}
+	return false
+}
+
+// isInsert reports whether text inserts rows
+func isInsert(text string) bool {
+	switch parseStatement(text).verb {
+	case "INSERT", "REPLACE":
+		return true
+	}
+	return false
+}
+
+// containsFold reports whether substr is in s, ignoring ASCII case
+func containsFold(s, substr string) bool {
+	n := len(substr)
+	for i := 0; i+n <= len(s); i++ {
+		if strings.EqualFold(s[i:i+n], substr) {
+			return true
+		}
+	}
+	return false
+}
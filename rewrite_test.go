@@ -0,0 +1,86 @@
+package mysqldump
+
+import (
+	"testing"
+)
+
+func Test_rewriters(t *testing.T) {
+	tests := []struct {
+		name     string
+		rewriter StatementRewriter
+		text     string
+		want     string
+	}{
+		{
+			name:     "strip quoted definer",
+			rewriter: StripDefiner(),
+			text:     "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`localhost` SQL SECURITY DEFINER VIEW `v` AS select 1",
+			want:     "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v` AS select 1",
+		},
+		{
+			name:     "strip definer in conditional comment",
+			rewriter: StripDefiner(),
+			text:     "/*!50003 CREATE*/ /*!50017 DEFINER='app'@'%'*/ /*!50003 TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1 */",
+			want:     "/*!50003 CREATE*/ /*!50017 */ /*!50003 TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1 */",
+		},
+		{
+			name:     "strip current user definer",
+			rewriter: StripDefiner(),
+			text:     "CREATE DEFINER = CURRENT_USER() PROCEDURE p() SELECT 1",
+			want:     "CREATE PROCEDURE p() SELECT 1",
+		},
+		{
+			name:     "definer column of a trigger body is kept",
+			rewriter: StripDefiner(),
+			text:     "CREATE DEFINER=`root`@`%` TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.definer = CURRENT_USER()",
+			want:     "CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.definer = CURRENT_USER()",
+		},
+		{
+			name:     "definer in data is kept",
+			rewriter: StripDefiner(),
+			text:     "INSERT INTO `t` VALUES ('DEFINER=`root`@`%`')",
+			want:     "INSERT INTO `t` VALUES ('DEFINER=`root`@`%`')",
+		},
+		{
+			name:     "replace engine",
+			rewriter: ReplaceEngine("MyISAM", "InnoDB"),
+			text:     "CREATE TABLE `t` (\n  `myisam` int\n) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4",
+			want:     "CREATE TABLE `t` (\n  `myisam` int\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		{
+			name:     "replace engine with spaces",
+			rewriter: ReplaceEngine("myisam", "InnoDB"),
+			text:     "ALTER TABLE `t` ENGINE = MYISAM",
+			want:     "ALTER TABLE `t` ENGINE = InnoDB",
+		},
+		{
+			name:     "replace collation",
+			rewriter: ReplaceCollation("utf8mb4_0900_ai_ci", "utf8mb4_general_ci"),
+			text:     "CREATE TABLE `t` (\n  `a` varchar(10) COLLATE utf8mb4_0900_ai_ci DEFAULT 'utf8mb4_0900_ai_ci'\n) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci",
+			want:     "CREATE TABLE `t` (\n  `a` varchar(10) COLLATE utf8mb4_general_ci DEFAULT 'utf8mb4_general_ci'\n) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci",
+		},
+		{
+			name:     "replace collation of session",
+			rewriter: ReplaceCollation("utf8mb4_0900_ai_ci", "utf8mb4_general_ci"),
+			text:     "/*!50003 SET collation_connection  = utf8mb4_0900_ai_ci */",
+			want:     "/*!50003 SET collation_connection  = utf8mb4_general_ci */",
+		},
+		{
+			name:     "collation in data is kept",
+			rewriter: ReplaceCollation("utf8mb4_0900_ai_ci", "utf8mb4_general_ci"),
+			text:     "INSERT INTO `t` VALUES ('utf8mb4_0900_ai_ci')",
+			want:     "INSERT INTO `t` VALUES ('utf8mb4_0900_ai_ci')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rewriter.Rewrite(Statement{Text: tt.text})
+			if err != nil {
+				t.Fatalf("Rewrite() error = %v", err)
+			}
+			if len(got) != 1 || got[0].Text != tt.want {
+				t.Errorf("Rewrite() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package mysqldump
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+const defaultDelimiter = ";"
+
+// Statement is a single SQL statement read by a StatementScanner
+type Statement struct {
+	// Text of the statement without its delimiter and leading comments
+	Text string
+	// Offset is the byte offset of the statement in the input
+	Offset int64
+	// Line is the 1-based line number the statement starts on
+	Line int
+	// Delimiter that terminated the statement, empty at the end of the input
+	Delimiter string
+	// Comments are the "--" and "#" comment lines before the statement, without their markers
+	Comments []string
+}
+
+// StatementScanner splits a SQL script into statements the way the mysql client does.
+//
+// It understands quoted strings and identifiers, "--", "#" and "/* */" comments,
+// conditional comments ("/*!50003 ... */") and the client side DELIMITER command.
+// Comments before a statement are dropped, conditional comments are kept since
+// the server executes them.
+//
+//	sc := NewStatementScanner(f)
+//	for sc.Scan() {
+//		stmt := sc.Statement()
+//		...
+//	}
+//	if err := sc.Err(); err != nil {
+//		...
+//	}
+type StatementScanner struct {
+	r         *bufio.Reader
+	delimiter string
+	// NO_BACKSLASH_ESCAPES: backslash is not an escape character inside strings
+	noBackslashEscapes bool
+	// multibyte character set whose trailing bytes may be a backslash or a quote, if any
+	charset string
+
+	offset int64
+	line   int
+	stmt   Statement
+	err    error
+}
+
+// NewStatementScanner returns a StatementScanner reading from r, using ";" as delimiter
+func NewStatementScanner(r io.Reader) *StatementScanner {
+	return &StatementScanner{
+		r:         bufio.NewReaderSize(r, 64*1024),
+		delimiter: defaultDelimiter,
+		line:      1,
+	}
+}
+
+// Statement returns the statement read by the last call to Scan
+func (s *StatementScanner) Statement() Statement {
+	return s.stmt
+}
+
+// Err returns the first non-EOF error encountered by the scanner
+func (s *StatementScanner) Err() error {
+	return s.err
+}
+
+// Offset returns the number of bytes consumed so far
+func (s *StatementScanner) Offset() int64 {
+	return s.offset
+}
+
+// Delimiter returns the current statement delimiter
+func (s *StatementScanner) Delimiter() string {
+	return s.delimiter
+}
+
+// SetNoBackslashEscapes tells the scanner whether backslash escapes characters
+// inside strings, which depends on the NO_BACKSLASH_ESCAPES sql_mode
+func (s *StatementScanner) SetNoBackslashEscapes(enabled bool) {
+	s.noBackslashEscapes = enabled
+}
+
+// SetCharset tells the scanner the character set of the input, set by SET NAMES.
+// In big5, gbk, sjis, cp932 and gb18030 the trailing byte of a character may be 0x5C,
+// which then does not escape the next byte.
+func (s *StatementScanner) SetCharset(charset string) {
+	s.charset = ""
+	switch charset = strings.ToLower(charset); charset {
+	case "big5", "gbk", "sjis", "cp932", "gb18030":
+		s.charset = charset
+	}
+}
+
+// resume continues counting from pos, the input being positioned at pos.Offset
+func (s *StatementScanner) resume(pos RestorePosition) {
+	s.offset = pos.Offset
+	s.line = pos.Line
+	if pos.Delimiter != "" {
+		s.delimiter = pos.Delimiter
+	}
+}
+
+func (s *StatementScanner) readByte() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	s.offset++
+	if c == '\n' {
+		s.line++
+	}
+	return c, nil
+}
+
+// peekIs reports whether the next bytes of the input are p
+func (s *StatementScanner) peekIs(p string) bool {
+	b, _ := s.r.Peek(len(p))
+	return string(b) == p
+}
+
+// skip consumes n bytes
+func (s *StatementScanner) skip(n int) {
+	for i := 0; i < n; i++ {
+		if _, err := s.readByte(); err != nil {
+			return
+		}
+	}
+}
+
+// Scan advances to the next statement, which is then available through Statement.
+// It returns false at the end of the input or on error.
+func (s *StatementScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	var (
+		buf     bytes.Buffer
+		started bool
+		// inside a conditional comment, whose content is code
+		inCond bool
+		// comment lines before the statement
+		comments []string
+	)
+
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+				return false
+			}
+			text := strings.TrimSpace(buf.String())
+			if text == "" {
+				return false
+			}
+			s.stmt.Text = text
+			s.stmt.Delimiter = ""
+			return true
+		}
+
+		if !started {
+			switch {
+			case isSpace(c):
+				continue
+			case c == '-' && s.isDashComment():
+				s.skip(1)
+				comments = append(comments, s.readComment())
+				continue
+			case c == '#':
+				comments = append(comments, s.readComment())
+				continue
+			case c == '/' && s.peekIs("*") && !s.peekIs("*!") && !s.peekIs("*+"):
+				s.skipBlockComment()
+				continue
+			case (c == 'D' || c == 'd') && s.isDelimiterCommand():
+				s.readDelimiter()
+				continue
+			}
+			started = true
+			s.stmt = Statement{
+				Offset:   s.offset - 1,
+				Line:     s.line,
+				Comments: comments,
+			}
+			comments = nil
+		}
+
+		// statement delimiter
+		if c == s.delimiter[0] && s.peekIs(s.delimiter[1:]) {
+			s.skip(len(s.delimiter) - 1)
+			s.stmt.Text = strings.TrimSpace(buf.String())
+			s.stmt.Delimiter = s.delimiter
+			if s.stmt.Text == "" {
+				// empty statement, e.g. ";;" or a lone delimiter
+				started = false
+				comments = s.stmt.Comments
+				buf.Reset()
+				continue
+			}
+			return true
+		}
+
+		buf.WriteByte(c)
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			if err := s.copyQuoted(&buf, c); err != nil {
+				s.err = err
+				return false
+			}
+		case c == '-' && s.isDashComment():
+			s.copyLine(&buf)
+		case c == '#':
+			s.copyLine(&buf)
+		case c == '/' && s.peekIs("*!"), c == '/' && s.peekIs("*+"):
+			// conditional comment or optimizer hint, scanned as code
+			inCond = true
+		case c == '/' && s.peekIs("*"):
+			s.copyBlockComment(&buf)
+		case c == '*' && inCond && s.peekIs("/"):
+			s.skip(1)
+			buf.WriteByte('/')
+			inCond = false
+		}
+	}
+}
+
+// isDashComment reports whether the "-" just read starts a "-- " comment,
+// which needs a whitespace or control character after the second dash
+func (s *StatementScanner) isDashComment() bool {
+	b, _ := s.r.Peek(2)
+	if len(b) == 0 || b[0] != '-' {
+		return false
+	}
+	return len(b) == 1 || b[1] <= ' '
+}
+
+// isDelimiterCommand reports whether the "D" just read starts a DELIMITER command
+func (s *StatementScanner) isDelimiterCommand() bool {
+	const cmd = "ELIMITER"
+	b, _ := s.r.Peek(len(cmd) + 1)
+	if len(b) < len(cmd)+1 {
+		return false
+	}
+	return strings.EqualFold(string(b[:len(cmd)]), cmd) && (b[len(cmd)] == ' ' || b[len(cmd)] == '\t')
+}
+
+// readDelimiter reads the argument of a DELIMITER command up to the end of the line
+func (s *StatementScanner) readDelimiter() {
+	var buf bytes.Buffer
+	for {
+		c, err := s.readByte()
+		if err != nil || c == '\n' {
+			break
+		}
+		buf.WriteByte(c)
+	}
+	fields := strings.Fields(buf.String())
+	// fields[0] is "ELIMITER"
+	if len(fields) > 1 {
+		s.delimiter = fields[1]
+	}
+}
+
+// readComment reads a comment up to the end of the line
+func (s *StatementScanner) readComment() string {
+	var buf bytes.Buffer
+	for {
+		c, err := s.readByte()
+		if err != nil || c == '\n' {
+			break
+		}
+		buf.WriteByte(c)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+func (s *StatementScanner) copyLine(buf *bytes.Buffer) {
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			return
+		}
+		buf.WriteByte(c)
+		if c == '\n' {
+			return
+		}
+	}
+}
+
+func (s *StatementScanner) skipBlockComment() {
+	// opening "*", so that "/*/" does not close the comment
+	s.skip(1)
+	var prev byte
+	for {
+		c, err := s.readByte()
+		if err != nil || prev == '*' && c == '/' {
+			return
+		}
+		prev = c
+	}
+}
+
+func (s *StatementScanner) copyBlockComment(buf *bytes.Buffer) {
+	// opening "*", so that "/*/" does not close the comment
+	if c, err := s.readByte(); err == nil {
+		buf.WriteByte(c)
+	}
+	var prev byte
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			return
+		}
+		buf.WriteByte(c)
+		if prev == '*' && c == '/' {
+			return
+		}
+		prev = c
+	}
+}
+
+// copyQuoted copies a quoted string or identifier up to its closing quote.
+// Quotes are escaped by doubling them, or with a backslash inside strings.
+func (s *StatementScanner) copyQuoted(buf *bytes.Buffer, quote byte) error {
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			if err == io.EOF {
+				// unterminated, let the server report it
+				return nil
+			}
+			return err
+		}
+		buf.WriteByte(c)
+
+		if c >= 0x81 && s.charset != "" {
+			// copy the trailing bytes of a multibyte character as is, like Dump writes them
+			var char [4]byte
+			char[0] = c
+			next, _ := s.r.Peek(3)
+			copy(char[1:], next)
+			if n := mbCharLen(s.charset, char[:1+len(next)]); n > 1 {
+				for i := 1; i < n; i++ {
+					c, _ = s.readByte()
+					buf.WriteByte(c)
+				}
+				continue
+			}
+		}
+
+		switch {
+		case c == '\\' && quote != '`' && !s.noBackslashEscapes:
+			c, err = s.readByte()
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			buf.WriteByte(c)
+		case c == quote:
+			if !s.peekIs(string(quote)) {
+				return nil
+			}
+			s.skip(1)
+			buf.WriteByte(quote)
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
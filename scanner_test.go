@@ -0,0 +1,156 @@
+package mysqldump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatementScanner(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "simple",
+			input: "SELECT 1;\nSELECT 2;",
+			want:  []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:  "no trailing delimiter",
+			input: "SELECT 1;\nSELECT 2\n",
+			want:  []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:  "delimiter inside strings",
+			input: `INSERT INTO t VALUES ('a;b',"c;d",'it\'s;','x'';y');SELECT 1;`,
+			want:  []string{`INSERT INTO t VALUES ('a;b',"c;d",'it\'s;','x'';y')`, "SELECT 1"},
+		},
+		{
+			name:  "delimiter inside identifiers",
+			input: "SELECT `a;b` FROM `t``;`;",
+			want:  []string{"SELECT `a;b` FROM `t``;`"},
+		},
+		{
+			name:  "leading comments are dropped",
+			input: "-- ----\n-- Table structure for t;\n# hash; comment\n/* block; */\nDROP TABLE t;",
+			want:  []string{"DROP TABLE t"},
+		},
+		{
+			name:  "comments inside statements are kept",
+			input: "SELECT 1 -- one; two\n, 2 /* three; */;",
+			want:  []string{"SELECT 1 -- one; two\n, 2 /* three; */"},
+		},
+		{
+			name:  "double dash without space is not a comment",
+			input: "SELECT 1--1;",
+			want:  []string{"SELECT 1--1"},
+		},
+		{
+			name:  "conditional comments are kept",
+			input: "/*!40101 SET NAMES utf8mb4 */;\n/*!40000 ALTER TABLE `t` DISABLE KEYS */;",
+			want:  []string{"/*!40101 SET NAMES utf8mb4 */", "/*!40000 ALTER TABLE `t` DISABLE KEYS */"},
+		},
+		{
+			name: "trigger with DELIMITER",
+			input: "DELIMITER ;;\n" +
+				"/*!50003 SET SESSION SQL_MODE=\"\" */;;\n" +
+				"/*!50003 CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW BEGIN SET NEW.a = 1; SET NEW.b = ';'; END */;;\n" +
+				"DELIMITER ;\n" +
+				"/*!50003 SET SESSION SQL_MODE=@OLD_SQL_MODE */;\n",
+			want: []string{
+				"/*!50003 SET SESSION SQL_MODE=\"\" */",
+				"/*!50003 CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW BEGIN SET NEW.a = 1; SET NEW.b = ';'; END */",
+				"/*!50003 SET SESSION SQL_MODE=@OLD_SQL_MODE */",
+			},
+		},
+		{
+			name:  "custom delimiter",
+			input: "delimiter $$\nCREATE PROCEDURE p() BEGIN SELECT 1; END$$\ndelimiter ;\nCALL p();",
+			want:  []string{"CREATE PROCEDURE p() BEGIN SELECT 1; END", "CALL p()"},
+		},
+		{
+			name:  "empty statements",
+			input: ";;\n ; SELECT 1;;",
+			want:  []string{"SELECT 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			sc := NewStatementScanner(strings.NewReader(tt.input))
+			for sc.Scan() {
+				got = append(got, sc.Statement().Text)
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementScanner_position(t *testing.T) {
+	input := "-- header\nSELECT 1;\n\n  SELECT\n2;"
+	sc := NewStatementScanner(strings.NewReader(input))
+
+	var got []Statement
+	for sc.Scan() {
+		got = append(got, sc.Statement())
+	}
+	want := []Statement{
+		{Text: "SELECT 1", Offset: 10, Line: 2, Delimiter: ";", Comments: []string{"header"}},
+		{Text: "SELECT\n2", Offset: 23, Line: 4, Delimiter: ";"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %+v, want %+v", got, want)
+	}
+	if sc.Offset() != int64(len(input)) {
+		t.Errorf("Offset() = %d, want %d", sc.Offset(), len(input))
+	}
+}
+
+func TestStatementScanner_noBackslashEscapes(t *testing.T) {
+	sc := NewStatementScanner(strings.NewReader(`SELECT 'a\';SELECT 2;`))
+	sc.SetNoBackslashEscapes(true)
+
+	var got []string
+	for sc.Scan() {
+		got = append(got, sc.Statement().Text)
+	}
+	want := []string{`SELECT 'a\'`, "SELECT 2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %q, want %q", got, want)
+	}
+}
+
+func TestStatementScanner_charset(t *testing.T) {
+	// U+30BD KATAKANA SO in sjis ends with 0x5C, written unescaped by Dump
+	const input = "INSERT INTO t VALUES ('\x83\x5C');SELECT 2;"
+	tests := []struct {
+		charset string
+		want    []string
+	}{
+		{charset: "sjis", want: []string{"INSERT INTO t VALUES ('\x83\x5C')", "SELECT 2"}},
+		{charset: "GBK", want: []string{"INSERT INTO t VALUES ('\x83\x5C')", "SELECT 2"}},
+		// the backslash escapes the quote
+		{charset: "utf8mb4", want: []string{"INSERT INTO t VALUES ('\x83\x5C');SELECT 2;"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			sc := NewStatementScanner(strings.NewReader(input))
+			sc.SetCharset(tt.charset)
+
+			var got []string
+			for sc.Scan() {
+				got = append(got, sc.Statement().Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
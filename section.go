@@ -0,0 +1,68 @@
+package mysqldump
+
+import "strings"
+
+// marker comments written by Dump before each part of a table
+const (
+	markerTableStructure  = "Table structure for "
+	markerTableData       = "Dumping data for table "
+	markerViewPlaceholder = "Temporary view structure for "
+	markerView            = "Final view structure for "
+	// written before the views by older versions
+	markerViewLegacy  = "View structure for "
+	markerTriggers    = "Dump table triggers of "
+	markerTriggersEnd = "--------"
+)
+
+type sectionKind int
+
+const (
+	sectionNone sectionKind = iota
+	sectionStructure
+	sectionData
+	sectionView
+	sectionTrigger
+)
+
+// section is the part of a dump a statement belongs to
+type section struct {
+	kind  sectionKind
+	table string
+}
+
+// holds reports whether a statement read in a view or trigger section is part of it:
+// the views and triggers, the view placeholders and the session settings around them.
+// Anything else, such as the USE of the next database, ends the section.
+func (s section) holds(info statementInfo) bool {
+	switch {
+	case info.verb == "USE":
+		return false
+	case info.object == "VIEW", info.object == "TRIGGER":
+		return true
+	case info.verb == "SET":
+		return true
+	case info.object == "TABLE" && (info.verb == "CREATE" || info.verb == "DROP"):
+		return s.kind == sectionView && info.table == s.table
+	}
+	return false
+}
+
+// parseSectionMarker parses a comment line written by Dump before a section
+func parseSectionMarker(comment string) (section, bool) {
+	switch {
+	case strings.HasPrefix(comment, markerTableStructure):
+		return section{kind: sectionStructure, table: comment[len(markerTableStructure):]}, true
+	case strings.HasPrefix(comment, markerTableData):
+		return section{kind: sectionData, table: comment[len(markerTableData):]}, true
+	case strings.HasPrefix(comment, markerViewPlaceholder):
+		return section{kind: sectionView, table: comment[len(markerViewPlaceholder):]}, true
+	case strings.HasPrefix(comment, markerView):
+		return section{kind: sectionView, table: comment[len(markerView):]}, true
+	case strings.HasPrefix(comment, markerViewLegacy):
+		return section{kind: sectionView, table: comment[len(markerViewLegacy):]}, true
+	case strings.HasPrefix(comment, markerTriggers):
+		table := strings.TrimSuffix(comment[len(markerTriggers):], markerTriggersEnd)
+		return section{kind: sectionTrigger, table: table}, true
+	}
+	return section{}, false
+}
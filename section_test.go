@@ -0,0 +1,26 @@
+package mysqldump
+
+import "testing"
+
+func Test_parseSectionMarker(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    section
+		wantOk  bool
+	}{
+		{comment: "Table structure for t", want: section{kind: sectionStructure, table: "t"}, wantOk: true},
+		{comment: "Dumping data for table t", want: section{kind: sectionData, table: "t"}, wantOk: true},
+		{comment: "View structure for v", want: section{kind: sectionView, table: "v"}, wantOk: true},
+		{comment: "Dump table triggers of t--------", want: section{kind: sectionTrigger, table: "t"}, wantOk: true},
+		{comment: "----------------------------", wantOk: false},
+		{comment: "Dump completed", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			got, ok := parseSectionMarker(tt.comment)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("parseSectionMarker(%q) = %+v, %v, want %+v, %v", tt.comment, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
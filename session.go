@@ -0,0 +1,125 @@
+package mysqldump
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// innodb_lock_wait_timeout of WithBulkLoadSession, in seconds
+const bulkLockWaitTimeout = 3600
+
+// session variables saved by WithBulkLoadSession: the ones it tunes
+// and the ones the dump header changes
+var bulkLoadVariables = []string{
+	"foreign_key_checks",
+	"unique_checks",
+	"innodb_lock_wait_timeout",
+	"character_set_client",
+	"character_set_results",
+	"collation_connection",
+	"time_zone",
+}
+
+// tuneSession saves the session variables, then sets them up for a bulk load
+// (WithBulkLoadSession) or without binary logging (WithSkipBinlog)
+func (db *dbWrapper) tuneSession(o *sourceOption) error {
+	var (
+		vars   []string
+		tuning []string
+	)
+	if o.bulkLoad {
+		vars = append(vars, bulkLoadVariables...)
+		tuning = append(tuning, "foreign_key_checks=0", "unique_checks=0",
+			fmt.Sprintf("innodb_lock_wait_timeout=%d", bulkLockWaitTimeout))
+	}
+	if o.skipBinlog {
+		vars = append(vars, "sql_log_bin")
+		tuning = append(tuning, "sql_log_bin=0")
+	}
+
+	selects := make([]string, len(vars))
+	for i, name := range vars {
+		selects[i] = "@@SESSION." + name
+	}
+	rows, err := db.Query("SELECT " + strings.Join(selects, ", "))
+	if err != nil {
+		return err
+	}
+	values := make([]sql.NullString, len(vars))
+	dest := make([]interface{}, len(vars))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if rows.Next() {
+		err = rows.Scan(dest...)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	saved := make([]string, 0, len(vars))
+	for i, name := range vars {
+		if values[i].Valid {
+			saved = append(saved, fmt.Sprintf("%s=%s", name, sessionValue(values[i].String)))
+		}
+	}
+	db.saved = "SET SESSION " + strings.Join(saved, ", ") + ";"
+
+	_, err = db.Exec("SET SESSION " + strings.Join(tuning, ", ") + ";")
+	return err
+}
+
+// saveSession reads the database, sql_mode and autocommit of a session given by the caller.
+// close then ends what the restore left open and sets them back.
+func (db *dbWrapper) saveSession() error {
+	var (
+		mode       string
+		autocommit int
+		database   sql.NullString
+	)
+	if err := db.Conn.QueryRowContext(db.ctx, "SELECT @@SESSION.sql_mode, @@SESSION.autocommit, DATABASE()").
+		Scan(&mode, &autocommit, &database); err != nil {
+		return err
+	}
+	db.restore = []string{fmt.Sprintf("SET SESSION sql_mode=%s, autocommit=%d;", sessionValue(mode), autocommit)}
+	if database.Valid {
+		db.restore = append(db.restore, fmt.Sprintf("USE %s;", quoteIdentifier(database.String)))
+	}
+	return nil
+}
+
+// close restores the session variables saved by tuneSession and saveSession,
+// then gives the connection back
+func (db *dbWrapper) close() error {
+	var stmts []string
+	if db.restore != nil {
+		// the transaction and locks left by a failed restore
+		stmts = append(stmts, "ROLLBACK;", "UNLOCK TABLES;")
+	}
+	if db.saved != "" {
+		stmts = append(stmts, db.saved)
+	}
+	stmts = append(stmts, db.restore...)
+	for _, q := range stmts {
+		if _, err := db.Exec(q); err != nil {
+			db.logger.Error("cannot restore the session", "phase", PhaseSession, "sql", q, "err", err)
+		}
+	}
+	if db.release == nil {
+		return nil
+	}
+	return db.release()
+}
+
+// sessionValue formats the value of a session variable for a SET statement
+func sessionValue(v string) string {
+	if v != "" && strings.Trim(v, "0123456789") == "" {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
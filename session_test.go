@@ -0,0 +1,25 @@
+package mysqldump
+
+import (
+	"testing"
+)
+
+func Test_sessionValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "1", want: "1"},
+		{value: "50", want: "50"},
+		{value: "utf8mb4", want: "'utf8mb4'"},
+		{value: "+00:00", want: "'+00:00'"},
+		{value: "SYSTEM", want: "'SYSTEM'"},
+		{value: "", want: "''"},
+		{value: "a'b", want: "'a''b'"},
+	}
+	for _, tt := range tests {
+		if got := sessionValue(tt.value); got != tt.want {
+			t.Errorf("sessionValue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package mysqldump
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// prefixes of the tables loaded, and of the live tables replaced, by WithShadowRestore
+const (
+	shadowPrefix = "_new_"
+	oldPrefix    = "_old_"
+)
+
+type (
+	// shadowRestore loads the tables of a restore into shadow copies,
+	// swapped with the live tables once everything is loaded
+	shadowRestore struct {
+		keepOld bool
+
+		mu     sync.Mutex
+		tables []*shadowTable
+		index  map[shadowKey]*shadowTable
+	}
+
+	shadowKey struct {
+		database, table string
+	}
+
+	shadowTable struct {
+		shadowKey
+		// the shadow copy was created and has to be swapped
+		created bool
+	}
+)
+
+func newShadowRestore(keepOld bool) *shadowRestore {
+	return &shadowRestore{
+		keepOld: keepOld,
+		index:   make(map[shadowKey]*shadowTable),
+	}
+}
+
+// lookup returns the table restored in a shadow copy, nil if there is none
+func (s *shadowRestore) lookup(database, table string) *shadowTable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index[shadowKey{database, table}]
+}
+
+// prepare registers the tables dropped or created by stmt, and defers views and
+// triggers until the tables are swapped. It reports whether stmt was deferred.
+func (s *shadowRestore) prepare(r *restorer, stmt Statement, info statementInfo) (bool, error) {
+	switch {
+	case r.section.kind == sectionView, r.section.kind == sectionTrigger,
+		info.object == "VIEW", info.object == "TRIGGER":
+		// views and triggers must see the live tables
+		r.deferred = append(r.deferred, deferredStatement{database: r.database, stmt: stmt})
+		return true, nil
+
+	case info.object == "TABLE" && (info.verb == "DROP" || info.verb == "CREATE"):
+		key := shadowKey{database: info.database, table: info.table}
+		if key.database == "" {
+			key.database = r.database
+		}
+		t := s.lookup(key.database, key.table)
+		if t == nil {
+			t = &shadowTable{shadowKey: key}
+			s.mu.Lock()
+			s.tables = append(s.tables, t)
+			s.index[key] = t
+			s.mu.Unlock()
+			// a copy left by an interrupted restore
+			if err := r.exec(Statement{}, "DROP TABLE IF EXISTS "+qualifiedName(key.database, shadowPrefix+key.table)); err != nil {
+				return false, err
+			}
+		}
+		if info.verb == "CREATE" {
+			if hasForeignKeys(stmt.Text) {
+				err := fmt.Errorf("%w: table %s", ErrShadowForeignKey, qualifiedName(key.database, key.table))
+				r.o.logger.Error("statement failed", "phase", PhaseExec, "db", key.database, "table", key.table, "err", err)
+				return false, &SourceError{Phase: PhaseExec, Database: key.database, Table: key.table, Offset: stmt.Offset, Line: stmt.Line, Err: err}
+			}
+			t.created = true
+		}
+	}
+	return false, nil
+}
+
+// rename makes a statement on a restored table target its shadow copy
+func (s *shadowRestore) rename(text, database string) string {
+	info := parseStatement(text)
+	if info.tableTok == nil {
+		return text
+	}
+	switch info.verb {
+	case "INSERT", "REPLACE", "UPDATE", "DELETE", "LOCK", "TRUNCATE":
+	case "CREATE", "DROP", "ALTER":
+		if info.object != "TABLE" {
+			return text
+		}
+	default:
+		return text
+	}
+	if info.database != "" {
+		database = info.database
+	}
+	if s.lookup(database, info.table) == nil {
+		return text
+	}
+	return text[:info.tableTok.start] + quoteIdentifier(shadowPrefix+info.table) + text[info.tableTok.end:]
+}
+
+// swap replaces the live tables by their shadow copies with a single RENAME TABLE,
+// then drops the previous tables unless they are kept
+func (s *shadowRestore) swap(r *restorer) error {
+	var renames, olds []string
+	for _, t := range s.tables {
+		if !t.created {
+			continue
+		}
+		live := qualifiedName(t.database, t.table)
+		// a reference to the live table would follow it to its old copy
+		child, err := r.referencingTable(t.database, t.table)
+		if err != nil {
+			return err
+		}
+		if child != "" {
+			err = fmt.Errorf("%w: table %s is referenced by %s", ErrShadowForeignKey, live, child)
+			r.o.logger.Error("cannot swap the tables", "phase", PhaseExec, "db", t.database, "table", t.table, "err", err)
+			return &SourceError{Phase: PhaseExec, Database: t.database, Table: t.table, Err: err}
+		}
+		exists, err := r.tableExists(t.database, t.table)
+		if err != nil {
+			return err
+		}
+		if exists {
+			old := qualifiedName(t.database, oldPrefix+t.table)
+			renames = append(renames, live+" TO "+old)
+			olds = append(olds, old)
+		}
+		renames = append(renames, qualifiedName(t.database, shadowPrefix+t.table)+" TO "+live)
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	if len(olds) > 0 {
+		// old copies kept by a previous restore
+		if err := r.exec(Statement{}, "DROP TABLE IF EXISTS "+strings.Join(olds, ", ")); err != nil {
+			return err
+		}
+	}
+	if err := r.exec(Statement{}, "RENAME TABLE "+strings.Join(renames, ", ")); err != nil {
+		return err
+	}
+	if len(olds) == 0 {
+		return nil
+	}
+	if !s.keepOld {
+		return r.exec(Statement{}, "DROP TABLE "+strings.Join(olds, ", "))
+	}
+
+	// triggers moved with the old tables, their names are unique per database
+	for _, t := range s.tables {
+		if err := r.dropTriggers(t.database, oldPrefix+t.table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableExists reports whether database.table exists, in the current database if database is empty
+func (r *restorer) tableExists(database, table string) (bool, error) {
+	rows, err := r.db.Query("SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?", database, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	exists := rows.Next()
+	return exists, rows.Err()
+}
+
+// referencingTable returns a table with a foreign key on database.table, empty if there is none
+func (r *restorer) referencingTable(database, table string) (string, error) {
+	rows, err := r.db.Query("SELECT TABLE_SCHEMA, TABLE_NAME FROM information_schema.KEY_COLUMN_USAGE "+
+		"WHERE REFERENCED_TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND REFERENCED_TABLE_NAME = ? LIMIT 1", database, table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return "", rows.Err()
+	}
+	var childDatabase, child string
+	if err = rows.Scan(&childDatabase, &child); err != nil {
+		return "", err
+	}
+	return qualifiedName(childDatabase, child), nil
+}
+
+// hasForeignKeys reports whether a CREATE TABLE statement declares foreign keys
+func hasForeignKeys(text string) bool {
+	if !containsFold(text, "REFERENCES") {
+		return false
+	}
+	for _, tok := range tokenize(text, -1) {
+		if tok.is("REFERENCES") {
+			return true
+		}
+	}
+	return false
+}
+
+// dropTriggers drops the triggers of database.table
+func (r *restorer) dropTriggers(database, table string) error {
+	rows, err := r.db.Query("SELECT TRIGGER_NAME FROM information_schema.TRIGGERS WHERE EVENT_OBJECT_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND EVENT_OBJECT_TABLE = ?", database, table)
+	if err != nil {
+		return err
+	}
+	var triggers []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		triggers = append(triggers, name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range triggers {
+		if err = r.exec(Statement{}, "DROP TRIGGER IF EXISTS "+qualifiedName(database, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// qualifiedName quotes table, qualified with database unless it is empty
+func qualifiedName(database, table string) string {
+	if database == "" {
+		return quoteIdentifier(table)
+	}
+	return quoteIdentifier(database) + "." + quoteIdentifier(table)
+}
@@ -0,0 +1,104 @@
+package mysqldump
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_shadowRestore_rename(t *testing.T) {
+	s := newShadowRestore(false)
+	s.index[shadowKey{"db", "t"}] = &shadowTable{shadowKey: shadowKey{"db", "t"}, created: true}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "DROP TABLE IF EXISTS `t`", want: "DROP TABLE IF EXISTS `_new_t`"},
+		{text: "CREATE TABLE IF NOT EXISTS `t` (`a` int)", want: "CREATE TABLE IF NOT EXISTS `_new_t` (`a` int)"},
+		{text: "LOCK TABLES `t` WRITE", want: "LOCK TABLES `_new_t` WRITE"},
+		{text: "/*!40000 ALTER TABLE `t` DISABLE KEYS */", want: "/*!40000 ALTER TABLE `_new_t` DISABLE KEYS */"},
+		{text: "INSERT INTO `db`.`t` VALUES (1)", want: "INSERT INTO `db`.`_new_t` VALUES (1)"},
+		{text: "INSERT INTO `other`.`t` VALUES (1)", want: "INSERT INTO `other`.`t` VALUES (1)"},
+		{text: "INSERT INTO `u` VALUES (1)", want: "INSERT INTO `u` VALUES (1)"},
+		{text: "CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1", want: "CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1"},
+		{text: "DROP VIEW IF EXISTS `t`", want: "DROP VIEW IF EXISTS `t`"},
+	}
+	for _, tt := range tests {
+		if got := s.rename(tt.text, "db"); got != tt.want {
+			t.Errorf("rename(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func Test_shadowRestore_prepare_databases(t *testing.T) {
+	r := newDryRunRestorer("")
+	r.shadow = newShadowRestore(false)
+	handleAll(t, r, multiDatabaseDump)
+
+	var tables []shadowKey
+	for _, st := range r.shadow.tables {
+		tables = append(tables, st.shadowKey)
+	}
+	wantTables := []shadowKey{{"db1", "t"}, {"db2", "t2"}}
+	if !reflect.DeepEqual(tables, wantTables) {
+		t.Errorf("shadow tables = %v, want %v", tables, wantTables)
+	}
+	wantExecuted := []string{
+		"db1: USE `db1`",
+		"db1: CREATE TABLE `_new_t` (`a` int)",
+		"db2: USE `db2`",
+		"db2: CREATE TABLE `_new_t2` (`a` int)",
+	}
+	if got := executed(r); !reflect.DeepEqual(got, wantExecuted) {
+		t.Errorf("executed = %q\nwant %q", got, wantExecuted)
+	}
+	for _, d := range r.deferred {
+		if d.database != "db1" {
+			t.Errorf("deferred %q in %q, want db1", d.stmt.Text, d.database)
+		}
+	}
+}
+
+func Test_shadowRestore_foreignKeys(t *testing.T) {
+	const dump = "-- ----------------------------\n-- Table structure for parent\n-- ----------------------------\n" +
+		"CREATE TABLE `parent` (`id` int NOT NULL, PRIMARY KEY (`id`));\n" +
+		"-- ----------------------------\n-- Table structure for child\n-- ----------------------------\n" +
+		"CREATE TABLE `child` (\n  `id` int NOT NULL,\n  `parent_id` int DEFAULT NULL,\n" +
+		"  CONSTRAINT `child_ibfk_1` FOREIGN KEY (`parent_id`) REFERENCES `parent` (`id`)\n);\n"
+
+	r := newDryRunRestorer("db")
+	r.shadow = newShadowRestore(false)
+	r.sc = NewStatementScanner(strings.NewReader(dump))
+	var err error
+	for err == nil && r.sc.Scan() {
+		err = r.handle(r.sc.Statement())
+	}
+
+	var se *SourceError
+	if !errors.Is(err, ErrShadowForeignKey) || !errors.As(err, &se) || se.Table != "child" || se.Line != 8 {
+		t.Fatalf("handle() error = %v, want ErrShadowForeignKey on child at line 8", err)
+	}
+	wantExecuted := []string{"db: CREATE TABLE `_new_parent` (`id` int NOT NULL, PRIMARY KEY (`id`))"}
+	if got := executed(r); !reflect.DeepEqual(got, wantExecuted) {
+		t.Errorf("executed = %q\nwant %q", got, wantExecuted)
+	}
+}
+
+func Test_hasForeignKeys(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "CREATE TABLE `t` (`a` int)", want: false},
+		{text: "CREATE TABLE `t` (`a` int COMMENT 'references a', `references` int)", want: false},
+		{text: "CREATE TABLE `t` (`a` int, CONSTRAINT `fk` FOREIGN KEY (`a`) REFERENCES `p` (`id`))", want: true},
+		{text: "CREATE TABLE `t` (`a` int REFERENCES `p` (`id`))", want: true},
+	}
+	for _, tt := range tests {
+		if got := hasForeignKeys(tt.text); got != tt.want {
+			t.Errorf("hasForeignKeys(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
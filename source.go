@@ -1,26 +1,58 @@
 package mysqldump
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
 type (
 	sourceOption struct {
-		dryRun      bool
-		mergeInsert int
-		debug       bool
+		dryRun          bool
+		mergeInsert     int
+		debug           bool
+		continueOnError bool
+		// commit after this many statements or bytes, 0 means only at the end
+		commitStatements int
+		commitBytes      int64
+		// file the restore position is saved to after each commit
+		checkpointPath string
+		// number of tables loaded concurrently
+		parallelism int
+		// databases and tables to restore
+		filter restoreFilter
+		// database names of the dump replaced on restore
+		databaseMap map[string]string
+		// create the target databases of databaseMap
+		createDatabases bool
+		// transient errors retried, nil disables retries
+		retry *RetryPolicy
+		// applied to each statement before it is executed
+		rewriters []StatementRewriter
+		// load the tables into shadow copies swapped at the end
+		shadow        bool
+		shadowKeepOld bool
+		// tune the sessions for a bulk load
+		bulkLoad   bool
+		skipBinlog bool
+		// called with the progress of the restore
+		progress func(SourceProgress)
+		// logger of the restore, silent by default
+		logger *slog.Logger
 	}
 
 	SourceOption func(*sourceOption)
 )
 
+// WithDryRun Do not execute anything, the statements that would run are returned in RestoreReport.Plan
 func WithDryRun() SourceOption {
 	return func(o *sourceOption) {
 		o.dryRun = true
@@ -33,155 +65,456 @@ func WithMergeInsert(size int) SourceOption {
 	}
 }
 
+// WithDebug Log every statement executed, at debug level.
+// Without WithSourceLogger they are written to the standard error.
 func WithDebug() SourceOption {
 	return func(o *sourceOption) {
 		o.debug = true
 	}
 }
 
+// WithContinueOnError Keep going when a statement fails, like mysql --force.
+// Failed statements are logged and returned in RestoreReport.Failures.
+func WithContinueOnError() SourceOption {
+	return func(o *sourceOption) {
+		o.continueOnError = true
+	}
+}
+
+// dbWrapper runs every statement on one dedicated connection,
+// so session state (USE, SET, autocommit) applies to the statements after it
 type dbWrapper struct {
-	DB     *sql.DB
-	debug  bool
-	dryRun bool
+	Conn Querier
+	// gives the connection back to its pool, nil if it is not ours
+	release func() error
+	ctx     context.Context
+	logger  *slog.Logger
+	dryRun  bool
+	// SET statement restoring the session variables changed by WithBulkLoadSession
+	saved string
+	// statements giving a session of the caller back as it was, see saveSession
+	restore []string
 }
 
-func newDBWrapper(db *sql.DB, dryRun, debug bool) *dbWrapper {
+func newDBWrapper(ctx context.Context, conn Querier, release func() error, dryRun bool, logger *slog.Logger) *dbWrapper {
 	return &dbWrapper{
-		DB:     db,
-		dryRun: dryRun,
-		debug:  debug,
+		Conn:    conn,
+		release: release,
+		ctx:     ctx,
+		dryRun:  dryRun,
+		logger:  logger,
 	}
 }
 
+// Query Run a query, even in dry-run since it does not change anything
+func (db *dbWrapper) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	db.logger.Debug("query", "phase", PhaseExec, "sql", query)
+	return db.Conn.QueryContext(db.ctx, query, args...)
+}
+
 // Exec Execute SQL statement
 func (db *dbWrapper) Exec(query string, args ...interface{}) (sql.Result, error) {
-	if db.debug {
-		log.Printf("[DEBUG] [query]\n%s\n", query)
-	}
+	db.logger.Debug("exec", "phase", PhaseExec, "sql", query)
 
 	if db.dryRun {
 		return nil, nil
 	}
-	return db.DB.Exec(query, args...)
+	return db.Conn.ExecContext(db.ctx, query, args...)
 }
 
-// Source Import a writer source (file, stdOut, etc.) to a MySQL/MariaDB Database
-// nolint: gocyclo
-func Source(dsn string, reader io.Reader, opts ...SourceOption) error {
-	var (
-		err error
-		db  *sql.DB
-		o   sourceOption
-	)
+// WithCommitEvery Commit after the given number of statements or bytes of SQL,
+// whichever comes first, instead of once at the end. Zero disables a limit.
+// The position of the last commit is returned in RestoreReport.LastCommit.
+func WithCommitEvery(statements int, bytes int64) SourceOption {
+	return func(o *sourceOption) {
+		o.commitStatements = statements
+		o.commitBytes = bytes
+	}
+}
 
-	start := time.Now()
-	log.Printf("[info] [source] start at %s\n", start.Format(DEFAULT_LOG_TIMESTAMP))
+// WithRestoreCheckpoint Save the restore position to path after each commit.
+// When the file exists, Source resumes after the last committed statement,
+// seeking the input when it implements io.Seeker and skipping statements otherwise.
+// The file is removed once the restore completes.
+func WithRestoreCheckpoint(path string) SourceOption {
+	return func(o *sourceOption) {
+		o.checkpointPath = path
+	}
+}
 
-	defer func() {
-		end := time.Now()
-		log.Printf("[info] [source] end at %s, cost %s\n", end.Format(DEFAULT_LOG_TIMESTAMP), end.Sub(start))
-	}()
+// WithRestoreParallelism Load the data of up to n tables concurrently, each on its own connection.
+// Table structures are created first and in order, views, triggers and routines are created
+// once all data is loaded. Sections are found with the markers Dump writes before each table.
+func WithRestoreParallelism(n int) SourceOption {
+	return func(o *sourceOption) {
+		o.parallelism = n
+	}
+}
 
-	// iterate over existing plugins
-	// and execute it
-	for _, opt := range opts {
-		opt(&o)
+// WithOnlyDatabases Restore only the given databases of the dump
+func WithOnlyDatabases(databases ...string) SourceOption {
+	return func(o *sourceOption) {
+		o.filter.databases = toSet(databases)
+	}
+}
+
+// WithOnlyTables Restore only the given tables (structure, data and triggers) and views.
+// Names may be qualified as "database.table" when the dump holds several databases.
+func WithOnlyTables(tables ...string) SourceOption {
+	return func(o *sourceOption) {
+		o.filter.tables = toSet(tables)
+	}
+}
+
+// WithSkipTables Restore everything but the given tables and views,
+// named like in WithOnlyTables
+func WithSkipTables(tables ...string) SourceOption {
+	return func(o *sourceOption) {
+		o.filter.skipTables = toSet(tables)
+	}
+}
+
+// WithDatabaseMap Restore the databases of the dump under other names, e.g.
+// {"prod_orders": "staging_orders"}. USE, CREATE DATABASE and database
+// qualified identifiers are rewritten. Filters still use the names of the dump.
+func WithDatabaseMap(m map[string]string) SourceOption {
+	return func(o *sourceOption) {
+		o.databaseMap = m
+	}
+}
+
+// WithCreateDatabases Create the target databases of WithDatabaseMap when they do not exist
+func WithCreateDatabases() SourceOption {
+	return func(o *sourceOption) {
+		o.createDatabases = true
+	}
+}
+
+// WithRetry Retry the statements failing with a transient error, such as a deadlock
+// or a dropped connection, replaying the transaction in progress (see RetryPolicy)
+func WithRetry(policy RetryPolicy) SourceOption {
+	return func(o *sourceOption) {
+		defaults := DefaultRetryPolicy()
+		if policy.MaxAttempts <= 0 {
+			policy.MaxAttempts = defaults.MaxAttempts
+		}
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = defaults.InitialBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = defaults.MaxBackoff
+		}
+		if len(policy.ErrorNumbers) == 0 {
+			policy.ErrorNumbers = defaults.ErrorNumbers
+		}
+		o.retry = &policy
+	}
+}
+
+// WithRewriter Change the statements before they are executed, e.g. with StripDefiner,
+// ReplaceEngine or ReplaceCollation. Rewriters are applied in the order given.
+func WithRewriter(rewriters ...StatementRewriter) SourceOption {
+	return func(o *sourceOption) {
+		o.rewriters = append(o.rewriters, rewriters...)
+	}
+}
+
+// WithShadowRestore Load each table into a `_new_<table>` copy, then swap all of them
+// with the live tables at once with a single RENAME TABLE, so readers never see partial tables.
+// Views and triggers are created after the swap. The replaced tables are dropped,
+// or kept as `_old_<table>` (without their triggers) when keepOld is true.
+// Tables with foreign keys, or referenced by one, are rejected with ErrShadowForeignKey.
+func WithShadowRestore(keepOld bool) SourceOption {
+	return func(o *sourceOption) {
+		o.shadow = true
+		o.shadowKeepOld = keepOld
+	}
+}
+
+// WithBulkLoadSession Tune the restore sessions for a bulk load: foreign key and unique checks
+// are disabled and innodb_lock_wait_timeout raised to an hour. These variables, and the
+// character set and time zone the dump header changes, are restored afterwards, even on error.
+func WithBulkLoadSession() SourceOption {
+	return func(o *sourceOption) {
+		o.bulkLoad = true
+	}
+}
+
+// WithSkipBinlog Do not write the restore to the binary log (sql_log_bin=0), so it is not
+// replicated. Requires the SYSTEM_VARIABLES_ADMIN or SUPER privilege.
+func WithSkipBinlog() SourceOption {
+	return func(o *sourceOption) {
+		o.skipBinlog = true
 	}
+}
+
+// WithProgress Call fn about every second with the progress of the restore, and once at the end.
+// The percentage and ETA are known when the reader is an *os.File or implements Size() int64.
+func WithProgress(fn func(SourceProgress)) SourceOption {
+	return func(o *sourceOption) {
+		o.progress = fn
+	}
+}
+
+// WithSourceLogger Log the restore with l, with the db, table, phase and rows as attributes.
+// Nothing is logged by default.
+func WithSourceLogger(l *slog.Logger) SourceOption {
+	return func(o *sourceOption) {
+		o.logger = l
+	}
+}
+
+// Source Import a writer source (file, stdOut, etc.) to a MySQL/MariaDB Database.
+// The returned report lists the statements executed and, with WithContinueOnError, the ones that failed.
+func Source(dsn string, reader io.Reader, opts ...SourceOption) (*RestoreReport, error) {
+	o := newSourceOption(opts)
 
 	// parse DSN options
 	cfg, err := parseDSN(dsn)
 	if err != nil {
-		log.Printf("[parse-dsn] [error] %v \n", err)
-		return err
+		o.logger.Error("invalid DSN", "phase", PhaseConnect, "err", err)
+		return nil, &SourceError{Phase: PhaseConnect, Err: err}
 	}
 
-	dbName := cfg.DBName
-
 	// Open database
-	db, err = sql.Open("mysql", dsn)
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		log.Printf("[error] %v\n", err)
-		return err
+		o.logger.Error("cannot open the database", "phase", PhaseConnect, "err", err)
+		return nil, &SourceError{Phase: PhaseConnect, Err: err}
 	}
 	defer db.Close()
+	db.SetConnMaxLifetime(time.Hour)
 
-	// DB Wrapper
-	dbWrapper := newDBWrapper(db, o.dryRun, o.debug)
+	return o.source(context.Background(), db, reader, cfg.DBName)
+}
+
+// SourceDB Import a writer source like Source, on an existing connection pool (*sql.DB),
+// connection (*sql.Conn) or any Querier, e.g. one configured with a custom mysql.Config.
+// A pool is asked for a dedicated connection, anything else is used as is: statements
+// run in its current database and the restore commits on it. The dedicated connection is
+// closed afterwards rather than given back with the session state of the dump; any other
+// Querier gets its database, sql_mode and autocommit back, and is rolled back and unlocked.
+// WithRestoreParallelism and reconnecting on retries need a *sql.DB.
+func SourceDB(ctx context.Context, db Querier, reader io.Reader, opts ...SourceOption) (*RestoreReport, error) {
+	o := newSourceOption(opts)
+	return o.source(ctx, db, reader, "")
+}
 
-	// Use database
-	if _, err = dbWrapper.Exec(fmt.Sprintf("USE %s;", dbName)); err != nil {
-		log.Printf("[error] %v\n", err)
-		return err
+func newSourceOption(opts []SourceOption) *sourceOption {
+	var o sourceOption
+	// iterate over existing plugins
+	// and execute it
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = newLogger(o.debug, false)
 	}
-	db.SetConnMaxLifetime(3600)
+	return &o
+}
 
-	// set autocommit
-	_, err = dbWrapper.Exec("SET autocommit=0;")
+// source restores reader on db, selecting dbName first unless it is empty
+// nolint: gocyclo
+func (o *sourceOption) source(ctx context.Context, db Querier, reader io.Reader, dbName string) (*RestoreReport, error) {
+	start := time.Now()
+	o.logger.Info("source started")
+	defer func() {
+		o.logger.Info("source finished", "duration", time.Since(start))
+	}()
+
+	pool, _ := db.(*sql.DB)
+	if o.parallelism > 1 && pool == nil {
+		return nil, errors.New("WithRestoreParallelism needs a *sql.DB")
+	}
+
+	// pin a single connection for the whole restore
+	w, err := openSession(ctx, db, o, dbName)
 	if err != nil {
-		log.Printf("[error] %v\n", err)
-		return err
+		return nil, err
 	}
+	defer func() {
+		w.close()
+	}()
 
-	r := bufio.NewReader(reader)
-	for {
-		line, err := r.ReadString(';')
-		if err != nil {
-			if err == io.EOF {
-				break
+	if o.createDatabases {
+		for _, target := range mappedDatabases(o.databaseMap) {
+			if _, err = w.Exec("CREATE DATABASE IF NOT EXISTS " + quoteIdentifier(target)); err != nil {
+				o.logger.Error("cannot create the database", "phase", PhaseConnect, "db", target, "err", err)
+				return nil, &SourceError{Phase: PhaseConnect, Database: target, Err: err}
 			}
-			log.Printf("[error] %v\n", err)
-			return err
-		}
-
-		ssql := string(line)
-		ssql = trim(ssql)
-
-		if o.mergeInsert > 1 && strings.HasPrefix(ssql, "INSERT INTO") {
-			var insertSQLs []string
-			insertSQLs = append(insertSQLs, ssql)
-
-			for i := 0; i < o.mergeInsert-1; i++ {
-				line, err := r.ReadString(';')
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					log.Printf("[error] %v\n", err)
-					return err
-				}
-
-				ssql2 := string(line)
-				ssql2 = trim(ssql2)
-				if strings.HasPrefix(ssql2, "INSERT INTO") {
-					insertSQLs = append(insertSQLs, ssql2)
-					continue
-				}
-				break
-			}
-			// INSERT
-			ssql, err = mergeInsert(insertSQLs)
-			if err != nil {
-				log.Printf("[error] [mergeInsert] %v\n", err)
-				return err
+		}
+	}
+
+	var (
+		cp       *checkpointer
+		seeked   bool
+		progress *progressTracker
+	)
+	if o.progress != nil {
+		// before the checkpoint wraps the reader
+		progress = newProgressTracker(o.progress, reader)
+	}
+	if o.checkpointPath != "" {
+		if o.parallelism > 1 {
+			return nil, errors.New("WithRestoreCheckpoint cannot be combined with WithRestoreParallelism")
+		}
+		if o.shadow {
+			return nil, errors.New("WithRestoreCheckpoint cannot be combined with WithShadowRestore")
+		}
+		if reader, cp, seeked, err = openCheckpoint(o.checkpointPath, reader); err != nil {
+			o.logger.Error("cannot open the checkpoint", "phase", PhaseCheckpoint, "err", err)
+			return nil, &SourceError{Phase: PhaseCheckpoint, Err: err}
+		}
+	}
+
+	r := newRestorer(o, w, NewStatementScanner(reader), dbName)
+	r.cp = cp
+	r.progress = progress
+	if pool != nil {
+		// a lost session is replaced when retrying
+		r.open = func() (*dbWrapper, error) {
+			s, err := openSession(ctx, pool, o, dbName)
+			if err == nil {
+				w = s
 			}
+			return s, err
+		}
+	}
+	if o.shadow {
+		r.shadow = newShadowRestore(o.shadowKeepOld)
+	}
+	if o.parallelism > 1 {
+		r.par = newParallelLoader(ctx, pool, o, dbName)
+		r.par.shadow = r.shadow
+		r.par.progress = r.progress
+		defer r.par.close()
+	}
+	if cp != nil && cp.resume != nil {
+		if err = r.resume(cp.resume, seeked); err != nil {
+			return r.report, sourceError(PhaseCheckpoint, err)
 		}
+	}
+	if err = r.run(); err != nil {
+		return r.report, sourceError(PhaseExec, err)
+	}
+
+	if _, err = w.Exec("SET autocommit=1;"); err != nil {
+		o.logger.Error("cannot restore autocommit", "phase", PhaseCommit, "err", err)
+		return r.report, &SourceError{Phase: PhaseCommit, Err: err}
+	}
+	return r.report, nil
+}
+
+// insertTarget returns the part of an INSERT statement before its VALUES
+func insertTarget(insertSQL string) string {
+	if i := strings.Index(insertSQL, "VALUES"); i != -1 {
+		return insertSQL[:i]
+	}
+	return insertSQL
+}
+
+// sqlModeEscapes reports whether stmt sets the sql_mode and,
+// if so, whether backslash stops being an escape character
+func sqlModeEscapes(stmt string) (noBackslash, ok bool) {
+	upper := strings.ToUpper(stmt)
+	if strings.HasPrefix(upper, "/*!") {
+		upper = strings.TrimLeft(upper[3:], "0123456789 ")
+	}
+	if !strings.HasPrefix(upper, "SET") || !strings.Contains(upper, "SQL_MODE") {
+		return false, false
+	}
+	return strings.Contains(upper, noBackslashEscapes), true
+}
+
+// openSession pins a connection of a pool, or takes any other Querier as is,
+// and prepares it for a restore
+func openSession(ctx context.Context, db Querier, o *sourceOption, dbName string) (*dbWrapper, error) {
+	var (
+		q       = db
+		release func() error
+	)
+	if pool, ok := db.(*sql.DB); ok {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			o.logger.Error("cannot open a session", "phase", PhaseSession, "err", err)
+			return nil, &SourceError{Phase: PhaseSession, Database: dbName, Err: err}
+		}
+		q = conn
+		release = func() error {
+			return discardConn(conn)
+		}
+	}
+	w := newDBWrapper(ctx, q, release, o.dryRun, o.logger)
+	fail := func(err error) (*dbWrapper, error) {
+		w.close()
+		return nil, &SourceError{Phase: PhaseSession, Database: dbName, Err: err}
+	}
+
+	var err error
+
+	// the session of the caller is given back as it was
+	if release == nil {
+		if err = w.saveSession(); err != nil {
+			o.logger.Error("cannot read the session", "phase", PhaseSession, "err", err)
+			return fail(err)
+		}
+	}
 
-		if _, err = dbWrapper.Exec(ssql); err != nil {
-			log.Printf("[error] %v\n", err)
-			return err
+	// Use database, the dump may select its own
+	if dbName != "" {
+		if _, err = w.Exec(fmt.Sprintf("USE %s;", quoteIdentifier(dbName))); err != nil {
+			o.logger.Error("cannot select the database", "phase", PhaseSession, "db", dbName, "err", err)
+			return fail(err)
 		}
 	}
 
-	if _, err = dbWrapper.Exec("COMMIT;"); err != nil {
-		log.Printf("[error] %v\n", err)
-		return err
+	// string literals are escaped for this sql_mode,
+	// the dump header may change it
+	if _, err = w.Exec(fmt.Sprintf("SET SESSION SQL_MODE='%s';", defaultSQLMode)); err != nil {
+		o.logger.Error("cannot set the sql_mode", "phase", PhaseSession, "err", err)
+		return fail(err)
 	}
 
-	if _, err = dbWrapper.Exec("SET autocommit=1;"); err != nil {
-		log.Printf("[error] %v\n", err)
-		return err
+	if o.bulkLoad || o.skipBinlog {
+		if err = w.tuneSession(o); err != nil {
+			o.logger.Error("cannot tune the session", "phase", PhaseSession, "err", err)
+			return fail(err)
+		}
+	}
+
+	// set autocommit
+	if _, err = w.Exec("SET autocommit=0;"); err != nil {
+		o.logger.Error("cannot disable autocommit", "phase", PhaseSession, "err", err)
+		return fail(err)
+	}
+	return w, nil
+}
+
+// SourceDir Import a directory-format dump: every *.sql file of dir, in name order,
+// for instance one Dump output per table. Combined with WithRestoreParallelism
+// the data of the files is loaded concurrently.
+func SourceDir(dsn string, dir string, opts ...SourceOption) (*RestoreReport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var (
+		readers []io.Reader
+		size    int64
+	)
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		// files may not end with a newline
+		readers = append(readers, f, strings.NewReader("\n"))
+		size += inputSize(f) + 1
 	}
-	return nil
+	return Source(dsn, sizedReader{Reader: io.MultiReader(readers...), size: size}, opts...)
 }
 
 /*
@@ -0,0 +1,245 @@
+package mysqldump
+
+import (
+	"strings"
+)
+
+// sqlToken is a word, quoted identifier, string literal or punctuation character
+// of a statement, with its position in the statement text
+type sqlToken struct {
+	text string
+	// backtick quoted identifier, text holds the unquoted name
+	ident bool
+	// string literal
+	str        bool
+	start, end int
+}
+
+// is reports whether the token is the keyword kw (case insensitive)
+func (t sqlToken) is(kw string) bool {
+	return !t.ident && !t.str && strings.EqualFold(t.text, kw)
+}
+
+// tokenize splits the first max tokens of a statement, max < 0 means all of them.
+// Comments are skipped and conditional comments are read as code.
+func tokenize(text string, max int) []sqlToken {
+	var tokens []sqlToken
+
+	for i := 0; i < len(text) && (max < 0 || len(tokens) < max); {
+		c := text[i]
+		switch {
+		case isSpace(c):
+			i++
+
+		case strings.HasPrefix(text[i:], "/*!"):
+			// conditional comment: skip the version number
+			i += 3
+			for i < len(text) && text[i] >= '0' && text[i] <= '9' {
+				i++
+			}
+		case strings.HasPrefix(text[i:], "*/"):
+			// end of a conditional comment
+			i += 2
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end == -1 {
+				return tokens
+			}
+			i += end + 4
+		case c == '#', strings.HasPrefix(text[i:], "--") && (i+2 == len(text) || text[i+2] <= ' '):
+			end := strings.IndexByte(text[i:], '\n')
+			if end == -1 {
+				return tokens
+			}
+			i += end + 1
+
+		case c == '`':
+			var name strings.Builder
+			j := i + 1
+			for j < len(text) {
+				if text[j] == '`' {
+					if j+1 < len(text) && text[j+1] == '`' {
+						name.WriteByte('`')
+						j += 2
+						continue
+					}
+					break
+				}
+				name.WriteByte(text[j])
+				j++
+			}
+			if j >= len(text) {
+				// unterminated
+				j = len(text) - 1
+			}
+			tokens = append(tokens, sqlToken{text: name.String(), ident: true, start: i, end: j + 1})
+			i = j + 1
+
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(text) {
+				if text[j] == '\\' {
+					j += 2
+					continue
+				}
+				if text[j] == c {
+					if j+1 < len(text) && text[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(text) {
+				// unterminated
+				j = len(text) - 1
+			}
+			tokens = append(tokens, sqlToken{text: text[i : j+1], str: true, start: i, end: j + 1})
+			i = j + 1
+
+		case isWordChar(c):
+			j := i
+			for j < len(text) && isWordChar(text[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: text[i:j], start: i, end: j})
+			i = j
+
+		default:
+			tokens = append(tokens, sqlToken{text: text[i : i+1], start: i, end: i + 1})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
+
+// statementInfo describes what a statement does and which object it targets
+type statementInfo struct {
+	// first keyword of the statement, e.g. INSERT or CREATE
+	verb string
+	// kind of object created, altered or dropped, e.g. TABLE or VIEW
+	object string
+	// database the statement is qualified with, empty if none
+	database string
+	// table (or view) the statement targets, empty if none
+	table string
+	// tokens of the database and table names, if any
+	databaseTok, tableTok *sqlToken
+}
+
+// parseStatement extracts the verb and target table of a statement.
+// Only the statements written by Dump and the mysql client are understood,
+// the target of anything else is left empty.
+func parseStatement(text string) statementInfo {
+	tokens := tokenize(text, 32)
+
+	var info statementInfo
+	if len(tokens) == 0 {
+		return info
+	}
+	info.verb = strings.ToUpper(tokens[0].text)
+
+	// index of the token holding the target name
+	target := -1
+	next := func(i int, optional ...string) int {
+		for i < len(tokens) {
+			skipped := false
+			for _, kw := range optional {
+				if tokens[i].is(kw) {
+					i++
+					skipped = true
+					break
+				}
+			}
+			if !skipped {
+				break
+			}
+		}
+		return i
+	}
+
+	switch info.verb {
+	case "INSERT", "REPLACE":
+		target = next(1, "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY", "IGNORE", "INTO")
+	case "UPDATE":
+		target = next(1, "LOW_PRIORITY", "IGNORE")
+	case "DELETE":
+		target = next(1, "LOW_PRIORITY", "QUICK", "IGNORE", "FROM")
+	case "TRUNCATE":
+		target = next(1, "TABLE")
+	case "LOCK":
+		target = next(1, "TABLES", "TABLE")
+	case "RENAME":
+		target = next(1, "TABLE")
+	case "ALTER", "DROP", "CREATE":
+		i := next(1, "OR", "REPLACE", "TEMPORARY", "ONLINE", "OFFLINE", "IGNORE")
+		// view and trigger attributes: ALGORITHM = x, DEFINER = user@host, SQL SECURITY x
+		for i < len(tokens) {
+			if (tokens[i].is("ALGORITHM") || tokens[i].is("DEFINER")) && i+1 < len(tokens) && tokens[i+1].text == "=" {
+				i = skipAssignment(tokens, i)
+			} else if tokens[i].is("SQL") {
+				i = next(i, "SQL", "SECURITY", "INVOKER", "DEFINER")
+			} else {
+				break
+			}
+		}
+		if i >= len(tokens) {
+			break
+		}
+		info.object = strings.ToUpper(tokens[i].text)
+		switch info.object {
+		case "TABLE", "VIEW":
+			target = next(i+1, "IF", "NOT", "EXISTS")
+		case "TRIGGER":
+			// CREATE TRIGGER name {BEFORE|AFTER} event ON table
+			for j := i + 1; j < len(tokens); j++ {
+				if tokens[j].is("ON") {
+					target = j + 1
+					break
+				}
+			}
+		case "DATABASE", "SCHEMA":
+			if t := next(i+1, "IF", "NOT", "EXISTS"); t < len(tokens) {
+				info.database = tokens[t].text
+				info.databaseTok = &tokens[t]
+			}
+		}
+	case "USE":
+		if len(tokens) > 1 {
+			info.database = tokens[1].text
+			info.databaseTok = &tokens[1]
+		}
+	}
+
+	if target < 0 || target >= len(tokens) || tokens[target].str {
+		return info
+	}
+	if target+2 < len(tokens) && tokens[target+1].text == "." {
+		info.database = tokens[target].text
+		info.databaseTok = &tokens[target]
+		target += 2
+	}
+	info.table = tokens[target].text
+	info.tableTok = &tokens[target]
+	return info
+}
+
+// skipAssignment skips "name = value" where value may be user@host
+func skipAssignment(tokens []sqlToken, i int) int {
+	i++
+	if i < len(tokens) && tokens[i].text == "=" {
+		i++
+	}
+	if i < len(tokens) {
+		i++
+	}
+	if i+1 < len(tokens) && tokens[i].text == "@" {
+		i += 2
+	}
+	return i
+}
@@ -0,0 +1,55 @@
+package mysqldump
+
+import "testing"
+
+func Test_parseStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		verb     string
+		object   string
+		database string
+		table    string
+	}{
+		{name: "insert", text: "INSERT INTO `orders` (`id`) VALUES (1)", verb: "INSERT", table: "orders"},
+		{name: "insert ignore qualified", text: "INSERT IGNORE INTO `shop`.`orders` VALUES (1)", verb: "INSERT", database: "shop", table: "orders"},
+		{name: "quoted backtick", text: "INSERT INTO `a``b` VALUES (1)", verb: "INSERT", table: "a`b"},
+		{name: "create table", text: "CREATE TABLE IF NOT EXISTS `t` (`id` int)", verb: "CREATE", object: "TABLE", table: "t"},
+		{name: "drop table", text: "DROP TABLE IF EXISTS `t`", verb: "DROP", object: "TABLE", table: "t"},
+		{name: "conditional alter", text: "/*!40000 ALTER TABLE `t` DISABLE KEYS */", verb: "ALTER", object: "TABLE", table: "t"},
+		{name: "lock", text: "LOCK TABLES `t` WRITE", verb: "LOCK", table: "t"},
+		{
+			name:   "create view",
+			text:   "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS select 1",
+			verb:   "CREATE",
+			object: "VIEW",
+			table:  "v",
+		},
+		{
+			name:   "trigger",
+			text:   "/*!50003 CREATE TRIGGER `tr` BEFORE INSERT ON `t` FOR EACH ROW SET NEW.a = 1 */",
+			verb:   "CREATE",
+			object: "TRIGGER",
+			table:  "t",
+		},
+		{
+			name:   "mysqldump trigger",
+			text:   "/*!50003 CREATE*/ /*!50017 DEFINER=`root`@`localhost`*/ /*!50003 TRIGGER `tr` AFTER UPDATE ON `t` FOR EACH ROW BEGIN END */",
+			verb:   "CREATE",
+			object: "TRIGGER",
+			table:  "t",
+		},
+		{name: "create database", text: "CREATE DATABASE IF NOT EXISTS `shop`", verb: "CREATE", object: "DATABASE", database: "shop"},
+		{name: "use", text: "USE `shop`", verb: "USE", database: "shop"},
+		{name: "set", text: "/*!40101 SET NAMES utf8mb4 */", verb: "SET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseStatement(tt.text)
+			if got.verb != tt.verb || got.object != tt.object || got.database != tt.database || got.table != tt.table {
+				t.Errorf("parseStatement() = %s %s %q.%q, want %s %s %q.%q",
+					got.verb, got.object, got.database, got.table, tt.verb, tt.object, tt.database, tt.table)
+			}
+		})
+	}
+}
@@ -5,7 +5,7 @@ import "text/template"
 const (
 	header = `-- mysqldump
 -- Server Host: {{ .Host }}
--- Database(s): {{ join .Dbs ", " }}
+-- Database(s): {{ join .Dbs ", " | comment }}
 -- Start Time: {{ .Startime.Format "2006-01-02 15:04:05" }}
 -- ------------------------------------------------------
 -- Server version:	{{ .Version }}
@@ -13,12 +13,12 @@ const (
 /*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
 /*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;
 /*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION */;
- SET NAMES utf8mb4 ;
+ SET NAMES {{ .Charset }} ;
 /*!40103 SET @OLD_TIME_ZONE=@@TIME_ZONE */;
 /*!40103 SET TIME_ZONE='+00:00' */;
 /*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0 */;
 /*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;
-/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;
+/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='{{ .SQLMode }}' */;
 /*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
 `
 
@@ -48,7 +48,7 @@ func NewTemplate() (t Template, err error) {
 	t = Template{}
 
 	if t.Header, err = template.New("mysqldumpHeader").
-		Funcs(template.FuncMap{"join": joinS}).
+		Funcs(template.FuncMap{"join": joinS, "comment": commentName}).
 		Parse(header); err != nil {
 		return
 	}
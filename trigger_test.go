@@ -0,0 +1,46 @@
+package mysqldump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_formatTrigger(t *testing.T) {
+	o := dumpOption{isDropTable: true, SQLMode: defaultSQLMode}
+	trg := createTrigger{
+		name:                "trg",
+		statement:           "CREATE DEFINER=`root`@`%` TRIGGER `trg` BEFORE INSERT ON `t` FOR EACH ROW BEGIN SET NEW.a = 1; SET NEW.b = 2; END",
+		sqlMode:             "STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION",
+		characterSetClient:  "utf8mb4",
+		collationConnection: "utf8mb4_0900_ai_ci",
+	}
+
+	sc := NewStatementScanner(strings.NewReader(o.formatTrigger(trg)))
+	var got []string
+	for sc.Scan() {
+		got = append(got, sc.Statement().Text)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"DROP TRIGGER IF EXISTS `trg`",
+		"/*!50003 SET @saved_cs_client = @@character_set_client */",
+		"/*!50003 SET @saved_cs_results = @@character_set_results */",
+		"/*!50003 SET @saved_col_connection = @@collation_connection */",
+		"/*!50003 SET character_set_client = utf8mb4 */",
+		"/*!50003 SET character_set_results = utf8mb4 */",
+		"/*!50003 SET collation_connection = utf8mb4_0900_ai_ci */",
+		"/*!50003 SET SESSION SQL_MODE='STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION' */",
+		trg.statement,
+		"/*!50003 SET SESSION SQL_MODE='" + defaultSQLMode + "' */",
+		"/*!50003 SET character_set_client = @saved_cs_client */",
+		"/*!50003 SET character_set_results = @saved_cs_results */",
+		"/*!50003 SET collation_connection = @saved_col_connection */",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q\nwant %q", got, want)
+	}
+}
@@ -9,8 +9,6 @@ import (
 
 const DEFAULT_LOG_TIMESTAMP = "2006-01-02 15:04:05"
 
-var replacer *strings.Replacer
-
 func parseDSN(dsn string) (*mysql.Config, error) {
 	cfg, err := mysql.ParseDSN(dsn)
 	if err != nil {
@@ -27,25 +25,17 @@ func splitS(s, delimiter string) []string {
 	return strings.Split(s, delimiter)
 }
 
-func sanitize(input string) string {
-	if replacer == nil {
-		replacer = strings.NewReplacer(
-			"\x00", "\\0",
-			"'", "\\'",
-			"\"", "\\\"",
-			"\b", "\\b",
-			"\n", "\\n",
-			"\r", "\\r",
-			"\x1A", "\\Z", // ASCII 26 == x1A
-		)
-	}
-	return replacer.Replace(input)
+// quoteIdentifier quotes a database, table, column or trigger name
+// with backticks, doubling any backtick it contains
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
 }
 
-func untilNow(start time.Time) string {
-	return time.Since(start).String()
+// commentName makes a name safe to be written inside a "--" comment line
+func commentName(name string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(name)
 }
 
-func trim(s string) string {
-	return strings.TrimSpace(strings.TrimLeft(s, "\n"))
+func untilNow(start time.Time) string {
+	return time.Since(start).String()
 }
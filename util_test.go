@@ -0,0 +1,23 @@
+package mysqldump
+
+import "testing"
+
+func Test_quoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "orders", want: "`orders`"},
+		{name: "backtick", in: "a`b", want: "`a``b`"},
+		{name: "injection", in: "x` WRITE; DROP TABLE `y", want: "`x`` WRITE; DROP TABLE ``y`"},
+		{name: "quote", in: "it's", want: "`it's`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteIdentifier(tt.in); got != tt.want {
+				t.Errorf("quoteIdentifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package mysqldump
+
+// viewSecurity rewrites the DEFINER and SQL SECURITY clauses of a CREATE VIEW statement
+// following WithViewDefiner and WithViewSQLSecurity
+func (o dumpOption) viewSecurity(createSQL string) string {
+	if !o.isViewDefiner && o.viewSQLSecurity == "" {
+		return createSQL
+	}
+	// the clauses come before VIEW, the definition is left untouched
+	tokens := objectHeader(tokenize(createSQL, 32))
+	return splice(createSQL, tokens, func(i int) (int, string, bool) {
+		switch {
+		case o.isViewDefiner && tokens[i].is("DEFINER") && i+1 < len(tokens) && tokens[i+1].text == "=":
+			end := skipAssignment(tokens, i)
+			// DEFINER = CURRENT_USER()
+			if end+1 < len(tokens) && tokens[end].text == "(" && tokens[end+1].text == ")" {
+				end += 2
+			}
+			pos := tokens[end-1].end
+			if o.viewDefiner != "" {
+				return pos, "DEFINER=" + o.viewDefiner, true
+			}
+			// remove the space after the clause too
+			for pos < len(createSQL) && isSpace(createSQL[pos]) {
+				pos++
+			}
+			return pos, "", true
+		case o.viewSQLSecurity != "" && tokens[i].is("SQL") && i+2 < len(tokens) && tokens[i+1].is("SECURITY"):
+			return tokens[i+2].end, "SQL SECURITY " + o.viewSQLSecurity, true
+		}
+		return 0, "", false
+	})
+}
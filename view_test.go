@@ -0,0 +1,53 @@
+package mysqldump
+
+import "testing"
+
+func Test_viewSecurity(t *testing.T) {
+	const create = "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`localhost` SQL SECURITY DEFINER VIEW `v` AS select `t`.`definer` AS `definer` from `t`"
+	tests := []struct {
+		name string
+		opts []DumpOption
+		want string
+	}{
+		{
+			name: "preserved",
+			want: create,
+		},
+		{
+			name: "definer removed",
+			opts: []DumpOption{WithViewDefiner("")},
+			want: "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v` AS select `t`.`definer` AS `definer` from `t`",
+		},
+		{
+			name: "definer replaced",
+			opts: []DumpOption{WithViewDefiner("`app`@`%`")},
+			want: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `v` AS select `t`.`definer` AS `definer` from `t`",
+		},
+		{
+			name: "sql security replaced",
+			opts: []DumpOption{WithViewSQLSecurity("invoker")},
+			want: "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`localhost` SQL SECURITY INVOKER VIEW `v` AS select `t`.`definer` AS `definer` from `t`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newDumpOption(tt.opts)
+			if got := o.viewSecurity(create); got != tt.want {
+				t.Errorf("viewSecurity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseSectionMarker_views(t *testing.T) {
+	for _, comment := range []string{
+		markerViewPlaceholder + "`v`",
+		markerView + "`v`",
+		markerViewLegacy + "`v`",
+	} {
+		sec, ok := parseSectionMarker(comment)
+		if !ok || sec.kind != sectionView || sec.table != "`v`" {
+			t.Errorf("parseSectionMarker(%q) = %+v, %v", comment, sec, ok)
+		}
+	}
+}